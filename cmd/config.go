@@ -0,0 +1,100 @@
+/*
+Copyright © 2021 Michael Eder @edermi / twitter.com/michael_eder_
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"github.com/spf13/viper"
+)
+
+// envFlags maps sensitive flags to the environment variables they fall back to,
+// so credentials don't end up in process listings and the shell history
+var envFlags = map[string]string{
+	"basic-auth":  "SKWEEZ_BASIC_AUTH",
+	"proxy":       "SKWEEZ_PROXY",
+	"with-header": "SKWEEZ_HEADER",
+}
+
+// loadConfig sets the flags not given on the command line from the environment variables in envFlags
+// and the config file given by --config, or ~/.skweez.yaml if it exists. The keys of the file are the long flag names.
+// Flags take precedence over environment variables, which take precedence over the config file.
+func loadConfig(cmd *cobra.Command) (err error) {
+	defer func() {
+		// errors are caused by the config file or environment rather than the usage
+		if err != nil {
+			cmd.SilenceUsage = true
+		}
+	}()
+	path, err := cmd.Flags().GetString("config")
+	if err != nil {
+		return err
+	}
+	explicit := path != ""
+	if !explicit {
+		if home, err := os.UserHomeDir(); err == nil {
+			path = filepath.Join(home, ".skweez.yaml")
+		}
+	}
+	v := viper.New()
+	if path != "" {
+		v.SetConfigFile(path)
+		if err := v.ReadInConfig(); err != nil && (explicit || !errors.Is(err, fs.ErrNotExist)) {
+			return fmt.Errorf("invalid config file %s: %w", path, err)
+		}
+	}
+	for key := range v.AllSettings() {
+		if cmd.Flags().Lookup(key) == nil {
+			return fmt.Errorf("invalid config file %s: unknown flag %s", path, key)
+		}
+	}
+	keys := v.AllKeys()
+	// the flags of bound environment variables are set first, so the config file can't override them,
+	// not even with an alias like header for with-header
+	for name, env := range envFlags {
+		if err := v.BindEnv(name, env); err != nil {
+			return err
+		}
+		keys = append([]string{name}, keys...)
+	}
+	for _, key := range keys {
+		flag := cmd.Flags().Lookup(key)
+		if flag.Changed || flag.Name == "config" || !v.IsSet(key) {
+			continue
+		}
+		// single values are parsed like on the command line, lists replace the default of list flags
+		if value, ok := v.Get(key).(string); ok {
+			err = cmd.Flags().Set(flag.Name, value)
+		} else if slice, ok := flag.Value.(pflag.SliceValue); ok {
+			err = slice.Replace(v.GetStringSlice(key))
+			flag.Changed = true
+		} else {
+			err = cmd.Flags().Set(flag.Name, v.GetString(key))
+		}
+		if err != nil {
+			return fmt.Errorf("invalid value for %s in the config file or environment: %w", flag.Name, err)
+		}
+	}
+	return nil
+}
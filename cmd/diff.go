@@ -0,0 +1,74 @@
+/*
+Copyright © 2021 Michael Eder @edermi / twitter.com/michael_eder_
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+)
+
+var diffCmd = &cobra.Command{
+	Use:   "diff old.txt new.txt",
+	Short: "Print the words of a new word list missing in an old one",
+	Long: `diff compares two word lists, for example the results of two crawls of the same site,
+and prints the words only found in the new one, most frequent first.
+The lists contain one word per line, optionally followed by its count.`,
+	Args: cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		paramRemoved, err := cmd.Flags().GetBool("removed")
+		if err != nil {
+			return err
+		}
+		cmd.SilenceUsage = true
+		oldWords, err := loadWordlists(args[:1])
+		if err != nil {
+			return err
+		}
+		newWords, err := loadWordlists(args[1:])
+		if err != nil {
+			return err
+		}
+		output := bufio.NewWriter(os.Stdout)
+		// without --removed, the output is a plain word list again
+		addedPrefix := ""
+		if paramRemoved {
+			addedPrefix = "+"
+		}
+		for _, word := range sortedWords(newWords, "freq") {
+			if _, found := oldWords[word]; !found {
+				fmt.Fprintln(output, addedPrefix+word)
+			}
+		}
+		if paramRemoved {
+			for _, word := range sortedWords(oldWords, "freq") {
+				if _, found := newWords[word]; !found {
+					fmt.Fprintln(output, "-"+word)
+				}
+			}
+		}
+		return output.Flush()
+	},
+}
+
+func init() {
+	diffCmd.Flags().Bool("removed", false, "Also print the words of the old list missing in the new one. Added words are then prefixed with +, removed ones with -")
+	rootCmd.AddCommand(diffCmd)
+}
@@ -0,0 +1,113 @@
+/*
+Copyright © 2021 Michael Eder @edermi / twitter.com/michael_eder_
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"github.com/edermi/skweez/pkg/skweez"
+	"github.com/spf13/cobra"
+	"golang.org/x/exp/maps"
+	"golang.org/x/exp/slices"
+)
+
+// extractCmd shares the flags of rootCmd and its RunE, which calls extractFiles instead of crawling
+var extractCmd = &cobra.Command{
+	Use:   "extract file1.html file2.html",
+	Short: "Extract the words of local HTML files without crawling",
+	Long: `extract runs saved HTML files through the same word extraction and filters as a crawl
+and writes the results like the crawl would. Directories are processed recursively,
+glob patterns like "pages/*.html" are expanded and - reads from stdin.
+Flags about crawling have no effect.`,
+	Args: func(cmd *cobra.Command, args []string) error {
+		if err := loadConfig(cmd); err != nil {
+			return err
+		}
+		return cobra.MinimumNArgs(1)(cmd, args)
+	},
+}
+
+// extractFiles extracts the words of local files and writes them to the output
+func extractFiles(config *skweezConf, patterns []string) error {
+	words := make(map[string]int)
+	for word, count := range config.crawler.MergeWords {
+		words[word] += count
+	}
+	emails := make(map[string]bool)
+	numbers := make(map[string]bool)
+	extract := func(body []byte) {
+		extracted := skweez.ExtractAll(body, config.crawler)
+		for word, count := range extracted.Words {
+			words[word] += count
+		}
+		for _, email := range extracted.Emails {
+			emails[email] = true
+		}
+		for _, number := range extracted.Numbers {
+			numbers[number] = true
+		}
+	}
+	for _, pattern := range patterns {
+		if pattern == "-" {
+			body, err := io.ReadAll(os.Stdin)
+			if err != nil {
+				return err
+			}
+			extract(body)
+			continue
+		}
+		paths, err := filepath.Glob(pattern)
+		if err != nil {
+			return fmt.Errorf("invalid pattern %s: %w", pattern, err)
+		}
+		if len(paths) == 0 {
+			return fmt.Errorf("no files found for %s", pattern)
+		}
+		for _, path := range paths {
+			err := filepath.WalkDir(path, func(path string, entry fs.DirEntry, err error) error {
+				if err != nil || entry.IsDir() {
+					return err
+				}
+				body, err := os.ReadFile(path)
+				if err != nil {
+					return err
+				}
+				extract(body)
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+		}
+	}
+	sortedEmails := maps.Keys(emails)
+	slices.Sort(sortedEmails)
+	if err := outputEmails(config, sortedEmails); err != nil {
+		return err
+	}
+	sortedNumbers := maps.Keys(numbers)
+	slices.Sort(sortedNumbers)
+	if err := outputNumbers(config, sortedNumbers); err != nil {
+		return err
+	}
+	return outputResults(config, words)
+}
@@ -0,0 +1,157 @@
+/*
+Copyright © 2021 Michael Eder @edermi / twitter.com/michael_eder_
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package cmd
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+)
+
+// wordWriter writes a word list in one of the output formats
+type wordWriter interface {
+	// WriteWords writes all words along with their counts
+	WriteWords(words map[string]int) error
+}
+
+// streamWriter is implemented by the formats supporting --stream, which writes words as soon as they are found
+type streamWriter interface {
+	// WriteStream writes new words, their counts are not known yet
+	WriteStream(words []string) error
+}
+
+// newWordWriter returns the writer of the output format selected by the flags
+func newWordWriter(config *skweezConf, output io.Writer) wordWriter {
+	switch {
+	case config.jsonOutput:
+		return &JSONWriter{output: output, compact: config.jsonCompact}
+	case config.jsonlOutput:
+		return &JSONLWriter{output: output, sortMode: config.sortMode}
+	case config.csvOutput:
+		return &CSVWriter{output: output, sortMode: config.sortMode}
+	default:
+		return &PlainWriter{output: output, sortMode: config.sortMode, withCounts: config.withCounts}
+	}
+}
+
+// PlainWriter writes one word per line, optionally followed by its count
+type PlainWriter struct {
+	output     io.Writer
+	sortMode   string
+	withCounts bool
+}
+
+func (writer *PlainWriter) WriteWords(words map[string]int) error {
+	for _, word := range sortedWords(words, writer.sortMode) {
+		if _, err := io.WriteString(writer.output, formatLine(word, words[word], writer.withCounts)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (writer *PlainWriter) WriteStream(words []string) error {
+	for _, word := range words {
+		if _, err := io.WriteString(writer.output, word+"\n"); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// JSONWriter writes a single JSON object mapping the words to their counts
+type JSONWriter struct {
+	output  io.Writer
+	compact bool
+}
+
+func (writer *JSONWriter) WriteWords(words map[string]int) error {
+	// encoding/json sorts map keys, so the output is stable across runs
+	var jsonString []byte
+	var err error
+	if writer.compact {
+		jsonString, err = json.Marshal(words)
+	} else {
+		jsonString, err = json.MarshalIndent(words, "", "  ")
+	}
+	if err != nil {
+		return err
+	}
+	_, err = writer.output.Write(append(jsonString, '\n'))
+	return err
+}
+
+// JSONLWriter writes one JSON object per word and line
+type JSONLWriter struct {
+	output   io.Writer
+	sortMode string
+}
+
+func (writer *JSONLWriter) WriteWords(words map[string]int) error {
+	for _, word := range sortedWords(words, writer.sortMode) {
+		if _, err := io.WriteString(writer.output, jsonLine(word, words[word])); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (writer *JSONLWriter) WriteStream(words []string) error {
+	for _, word := range words {
+		// the count is left out while streaming
+		if _, err := io.WriteString(writer.output, jsonLine(word, 0)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// CSVWriter writes the words and their counts as CSV with a word,count header
+type CSVWriter struct {
+	output   io.Writer
+	sortMode string
+}
+
+func (writer *CSVWriter) WriteWords(words map[string]int) error {
+	csvWriter := csv.NewWriter(writer.output)
+	csvWriter.Write([]string{"word", "count"})
+	for _, word := range sortedWords(words, writer.sortMode) {
+		csvWriter.Write([]string{word, strconv.Itoa(words[word])})
+	}
+	csvWriter.Flush()
+	return csvWriter.Error()
+}
+
+// formatLine formats a word for plain text output, optionally followed by its count
+func formatLine(word string, count int, withCount bool) string {
+	if withCount {
+		return fmt.Sprintf("%s %d\n", word, count)
+	}
+	return fmt.Sprintf("%s\n", word)
+}
+
+// jsonLine formats a word as JSON object on its own line, the count is left out if 0
+func jsonLine(word string, count int) string {
+	line, _ := json.Marshal(struct {
+		Word  string `json:"word"`
+		Count int    `json:"count,omitempty"`
+	}{word, count})
+	return string(line) + "\n"
+}
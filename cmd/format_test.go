@@ -0,0 +1,85 @@
+/*
+Copyright © 2021 Michael Eder @edermi / twitter.com/michael_eder_
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package cmd
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestWordWriters(t *testing.T) {
+	words := map[string]int{"summer": 1, "password": 3, "secret": 3}
+	tests := []struct {
+		name   string
+		config skweezConf
+		want   string
+	}{
+		{"plain", skweezConf{sortMode: "alpha"}, "password\nsecret\nsummer\n"},
+		{"plain with counts", skweezConf{sortMode: "freq", withCounts: true}, "password 3\nsecret 3\nsummer 1\n"},
+		{"json", skweezConf{jsonOutput: true}, "{\n  \"password\": 3,\n  \"secret\": 3,\n  \"summer\": 1\n}\n"},
+		{"json compact", skweezConf{jsonOutput: true, jsonCompact: true}, "{\"password\":3,\"secret\":3,\"summer\":1}\n"},
+		{"jsonl", skweezConf{jsonlOutput: true, sortMode: "alpha"}, "{\"word\":\"password\",\"count\":3}\n{\"word\":\"secret\",\"count\":3}\n{\"word\":\"summer\",\"count\":1}\n"},
+		{"csv", skweezConf{csvOutput: true, sortMode: "freq"}, "word,count\npassword,3\nsecret,3\nsummer,1\n"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var output bytes.Buffer
+			if err := newWordWriter(&test.config, &output).WriteWords(words); err != nil {
+				t.Fatal(err)
+			}
+			if got := output.String(); got != test.want {
+				t.Errorf("got %q, want %q", got, test.want)
+			}
+		})
+	}
+}
+
+func TestWordWritersStream(t *testing.T) {
+	words := []string{"summer", "password"}
+	tests := []struct {
+		name   string
+		config skweezConf
+		want   string
+	}{
+		{"plain", skweezConf{}, "summer\npassword\n"},
+		// counts are not known while streaming, so they are left out
+		{"plain with counts", skweezConf{withCounts: true}, "summer\npassword\n"},
+		{"jsonl", skweezConf{jsonlOutput: true}, "{\"word\":\"summer\"}\n{\"word\":\"password\"}\n"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var output bytes.Buffer
+			stream, ok := newWordWriter(&test.config, &output).(streamWriter)
+			if !ok {
+				t.Fatal("writer does not support streaming")
+			}
+			if err := stream.WriteStream(words); err != nil {
+				t.Fatal(err)
+			}
+			if got := output.String(); got != test.want {
+				t.Errorf("got %q, want %q", got, test.want)
+			}
+		})
+	}
+	// JSON and CSV need all counts and cannot stream
+	for _, config := range []skweezConf{{jsonOutput: true}, {csvOutput: true}} {
+		if _, ok := newWordWriter(&config, &bytes.Buffer{}).(streamWriter); ok {
+			t.Errorf("writer %T must not support streaming", newWordWriter(&config, &bytes.Buffer{}))
+		}
+	}
+}
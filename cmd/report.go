@@ -0,0 +1,87 @@
+/*
+Copyright © 2021 Michael Eder @edermi / twitter.com/michael_eder_
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package cmd
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// urlStatus is the response of a visited URL
+type urlStatus struct {
+	URL         string `json:"url"`
+	Status      int    `json:"status"`
+	ContentType string `json:"content_type"`
+}
+
+// urlReport collects the responses of all visited URLs for --url-report
+type urlReport struct {
+	statuses map[string]urlStatus
+}
+
+// watchURLs registers a urlReport with the crawler config if --url-report is given, otherwise it returns nil
+func watchURLs(config *skweezConf) *urlReport {
+	if config.urlReport == "" {
+		return nil
+	}
+	report := &urlReport{statuses: make(map[string]urlStatus)}
+	config.crawler.OnStatus = func(url string, statusCode int, contentType string) {
+		// retried requests report again, the last response wins
+		report.statuses[url] = urlStatus{URL: url, Status: statusCode, ContentType: contentType}
+	}
+	return report
+}
+
+// write writes the report sorted by URL, as JSON if the path ends in .json, otherwise as CSV
+func (report *urlReport) write(path string) error {
+	if report == nil {
+		return nil
+	}
+	statuses := make([]urlStatus, 0, len(report.statuses))
+	for _, status := range report.statuses {
+		statuses = append(statuses, status)
+	}
+	sort.Slice(statuses, func(i, j int) bool {
+		return statuses[i].URL < statuses[j].URL
+	})
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if strings.HasSuffix(strings.ToLower(path), ".json") {
+		encoder := json.NewEncoder(file)
+		encoder.SetIndent("", "  ")
+		err = encoder.Encode(statuses)
+	} else {
+		writer := csv.NewWriter(file)
+		writer.Write([]string{"url", "status", "content_type"})
+		for _, status := range statuses {
+			writer.Write([]string{status.URL, strconv.Itoa(status.Status), status.ContentType})
+		}
+		writer.Flush()
+		err = writer.Error()
+	}
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
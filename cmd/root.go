@@ -17,88 +17,548 @@ along with this program. If not, see <http://www.gnu.org/licenses/>.
 package cmd
 
 import (
-	"encoding/json"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"embed"
 	"fmt"
-	"log"
+	"io"
+	"net"
+	"net/url"
 	"os"
+	"os/signal"
+	"path/filepath"
 	"regexp"
+	"sort"
+	"strconv"
 	"strings"
-	"unicode"
+	"syscall"
+	"time"
+	"unicode/utf8"
 
-	"github.com/gocolly/colly"
+	"github.com/andybalholm/cascadia"
+	"github.com/edermi/skweez/pkg/skweez"
+	"github.com/gobwas/glob"
 	"github.com/spf13/cobra"
-	"golang.org/x/exp/utf8string"
-	"golang.org/x/net/html"
+	"github.com/spf13/pflag"
+	"golang.org/x/exp/slices"
+	"golang.org/x/exp/slog"
+	"golang.org/x/net/idna"
+	"golang.org/x/net/publicsuffix"
 )
 
 type skweezConf struct {
-	debug      bool
-	depth      int
-	minLen     int
-	maxLen     int
-	scope      []string
-	output     string
-	noFilter   bool
-	jsonOutput bool
-	targets    []string
-	urlFilter  []*regexp.Regexp
-	onlyASCII  bool
-	userAgent  string
-	headers    []string
-}
-
-var validWordRegex = regexp.MustCompile(`^[a-zA-Z0-9]+.*[a-zA-Z0-9]$`)
-var stripTrailingSymbols = "!\"#$%&'()*+,-./:;<=>?@[\\]^_`{|}~"
+	output       string
+	jsonOutput   bool
+	sortMode     string
+	withCounts   bool
+	timeout      time.Duration
+	appendOutput bool
+	stream       bool
+	gzip         bool
+	csvOutput    bool
+	jsonCompact  bool
+	stdout       bool
+	minCount     int
+	emailsOutput string
+	dryRun       bool
+	progress     bool
+	summary      bool
+	outputDir    string
+	leet         bool
+	leetMax      int
+	mangleRules  []string
+	jsonlOutput  bool
+	urlReport    string
+	numberOutput string
+	crawler      skweez.Config
+}
+
+//go:embed stopwords/*.txt
+var builtinStopwords embed.FS
 
 var rootCmd = &cobra.Command{
 	Use:   "skweez domain1 domain2 domain3",
 	Short: "Sqeezes the words out of websites",
 	Long: `skweez is a fast and easy to use tool that allows you to (recursively)
 crawl websites to generate word lists.`,
-	Args: cobra.MinimumNArgs(1),
-	Run: func(cmd *cobra.Command, args []string) {
+	Args: func(cmd *cobra.Command, args []string) error {
+		// the config file may provide the targets, so it is loaded before the arguments are checked
+		if err := loadConfig(cmd); err != nil {
+			return err
+		}
+		targetsFile, err := cmd.Flags().GetString("targets-file")
+		if err != nil {
+			return err
+		}
+		depthMap, err := cmd.Flags().GetStringArray("depth-map")
+		if err != nil {
+			return err
+		}
+		if len(args) == 0 && targetsFile == "" && len(depthMap) == 0 && !stdinIsPiped() {
+			return fmt.Errorf("requires at least 1 target, --targets-file, --depth-map or targets piped via stdin")
+		}
+		return nil
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
 		// fetch cmd args
 		paramDebug, err := cmd.LocalFlags().GetBool("debug")
-		handleErr(err, false)
+		handleErr(err)
 		paramDepth, err := cmd.LocalFlags().GetInt("depth")
-		handleErr(err, false)
+		handleErr(err)
 		paramMinLen, err := cmd.LocalFlags().GetInt("min-word-length")
-		handleErr(err, false)
+		handleErr(err)
 		paramMaxLen, err := cmd.LocalFlags().GetInt("max-word-length")
-		handleErr(err, false)
+		handleErr(err)
+		paramMinUniqueChars, err := cmd.LocalFlags().GetInt("min-unique-chars")
+		handleErr(err)
+		paramMustContainDigit, err := cmd.LocalFlags().GetBool("must-contain-digit")
+		handleErr(err)
+		paramNoNumeric, err := cmd.LocalFlags().GetBool("no-numeric")
+		handleErr(err)
+		paramNumbersOnly, err := cmd.LocalFlags().GetBool("numbers-only")
+		handleErr(err)
+		paramWordPrefix, err := cmd.LocalFlags().GetString("word-prefix")
+		handleErr(err)
+		paramWordSuffix, err := cmd.LocalFlags().GetString("word-suffix")
+		handleErr(err)
+		paramIgnoreAffixCase, err := cmd.LocalFlags().GetBool("ignore-affix-case")
+		handleErr(err)
 		paramScope, err := cmd.LocalFlags().GetStringSlice("scope")
-		handleErr(err, false)
+		handleErr(err)
 		paramURLFilter, err := cmd.LocalFlags().GetString("url-filter")
-		handleErr(err, false)
+		handleErr(err)
+		paramExternalDepth, err := cmd.LocalFlags().GetInt("external-depth")
+		handleErr(err)
 		paramOutput, err := cmd.LocalFlags().GetString("output")
-		handleErr(err, false)
+		handleErr(err)
 		paramNoFilter, err := cmd.LocalFlags().GetBool("no-filter")
-		handleErr(err, false)
+		handleErr(err)
 		paramJsonOutput, err := cmd.LocalFlags().GetBool("json")
-		handleErr(err, false)
+		handleErr(err)
 		paramOnlyASCII, err := cmd.LocalFlags().GetBool("onlyascii")
-		handleErr(err, false)
+		handleErr(err)
 		paramUserAgent, err := cmd.LocalFlags().GetString("user-agent")
-		handleErr(err, false)
+		handleErr(err)
 		paramHeaders, err := cmd.LocalFlags().GetStringArray("with-header")
-		handleErr(err, false)
+		handleErr(err)
+		paramTargetsFile, err := cmd.LocalFlags().GetString("targets-file")
+		handleErr(err)
+		paramDelay, err := cmd.LocalFlags().GetDuration("delay")
+		handleErr(err)
+		paramRandomDelay, err := cmd.LocalFlags().GetDuration("random-delay")
+		handleErr(err)
+		paramParallelism, err := cmd.LocalFlags().GetInt("parallelism")
+		handleErr(err)
+		paramLimits, err := cmd.LocalFlags().GetStringArray("limit")
+		handleErr(err)
+		paramIgnoreRobots, err := cmd.LocalFlags().GetBool("ignore-robots")
+		handleErr(err)
+		paramUseSitemap, err := cmd.LocalFlags().GetBool("use-sitemap")
+		handleErr(err)
+		paramRequestTimeout, err := cmd.LocalFlags().GetDuration("request-timeout")
+		handleErr(err)
+		paramTimeout, err := cmd.LocalFlags().GetDuration("timeout")
+		handleErr(err)
+		paramProxies, err := cmd.LocalFlags().GetStringSlice("proxy")
+		handleErr(err)
+		paramSort, err := cmd.LocalFlags().GetString("sort")
+		handleErr(err)
+		paramWithCounts, err := cmd.LocalFlags().GetBool("with-counts")
+		handleErr(err)
+		paramUnicode, err := cmd.LocalFlags().GetBool("unicode")
+		handleErr(err)
+		paramIncludeAttrs, err := cmd.LocalFlags().GetStringSlice("include-attrs")
+		handleErr(err)
+		paramIncludeFormFields, err := cmd.LocalFlags().GetBool("include-form-fields")
+		handleErr(err)
+		paramNoMeta, err := cmd.LocalFlags().GetBool("no-meta")
+		handleErr(err)
+		paramStopwords, err := cmd.LocalFlags().GetString("stopwords")
+		handleErr(err)
+		paramCase, err := cmd.LocalFlags().GetString("case")
+		handleErr(err)
+		paramNormalize, err := cmd.LocalFlags().GetBool("normalize")
+		handleErr(err)
+		paramSplitIdentifiers, err := cmd.LocalFlags().GetBool("split-identifiers")
+		handleErr(err)
+		paramAppend, err := cmd.LocalFlags().GetBool("append")
+		handleErr(err)
+		paramStream, err := cmd.LocalFlags().GetBool("stream")
+		handleErr(err)
+		paramGzip, err := cmd.LocalFlags().GetBool("gzip")
+		handleErr(err)
+		paramCSV, err := cmd.LocalFlags().GetBool("csv")
+		handleErr(err)
+		paramJsonCompact, err := cmd.LocalFlags().GetBool("json-compact")
+		handleErr(err)
+		paramStdout, err := cmd.LocalFlags().GetBool("stdout")
+		handleErr(err)
+		paramMaxPages, err := cmd.LocalFlags().GetInt("max-pages")
+		handleErr(err)
+		paramUnlimited, err := cmd.LocalFlags().GetBool("i-know-unlimited")
+		handleErr(err)
+		paramBasicAuth, err := cmd.LocalFlags().GetString("basic-auth")
+		handleErr(err)
+		paramCookies, err := cmd.LocalFlags().GetString("cookies")
+		handleErr(err)
+		paramRetries, err := cmd.LocalFlags().GetInt("retries")
+		handleErr(err)
+		paramIncludeSubdomains, err := cmd.LocalFlags().GetBool("include-subdomains")
+		handleErr(err)
+		paramExcludeURLFilter, err := cmd.LocalFlags().GetStringArray("exclude-url-filter")
+		handleErr(err)
+		paramIgnoreQueryParams, err := cmd.LocalFlags().GetStringSlice("ignore-query-params")
+		handleErr(err)
+		paramMaxSamePath, err := cmd.LocalFlags().GetInt("max-same-path")
+		handleErr(err)
+		paramNoQueryCrawl, err := cmd.LocalFlags().GetBool("no-query-crawl")
+		handleErr(err)
+		paramAllowRevisit, err := cmd.LocalFlags().GetBool("allow-revisit")
+		handleErr(err)
+		paramNormalizeURLs, err := cmd.LocalFlags().GetBool("normalize-urls")
+		handleErr(err)
+		paramMaxWords, err := cmd.LocalFlags().GetInt("max-words")
+		handleErr(err)
+		paramStopAtMaxWords, err := cmd.LocalFlags().GetBool("stop-at-max-words")
+		handleErr(err)
+		paramMinCount, err := cmd.LocalFlags().GetInt("min-count")
+		handleErr(err)
+		paramLanguage, err := cmd.LocalFlags().GetString("language")
+		handleErr(err)
+		paramLanguageConfidence, err := cmd.LocalFlags().GetFloat64("language-confidence")
+		handleErr(err)
+		paramSplitPunctuation, err := cmd.LocalFlags().GetBool("split-punctuation")
+		handleErr(err)
+		paramSplitChars, err := cmd.LocalFlags().GetString("split-chars")
+		handleErr(err)
+		paramKeepOriginal, err := cmd.LocalFlags().GetBool("keep-original")
+		handleErr(err)
+		paramEmails, err := cmd.LocalFlags().GetBool("emails")
+		handleErr(err)
+		paramEmailsOutput, err := cmd.LocalFlags().GetString("emails-output")
+		handleErr(err)
+		paramIncludeScripts, err := cmd.LocalFlags().GetBool("include-scripts")
+		handleErr(err)
+		paramIncludeComments, err := cmd.LocalFlags().GetBool("include-comments")
+		handleErr(err)
+		paramIncludePDF, err := cmd.LocalFlags().GetBool("include-pdf")
+		handleErr(err)
+		paramDryRun, err := cmd.LocalFlags().GetBool("dry-run")
+		handleErr(err)
+		paramProgress, err := cmd.LocalFlags().GetBool("progress")
+		handleErr(err)
+		paramSummary, err := cmd.LocalFlags().GetBool("summary")
+		handleErr(err)
+		paramQuiet, err := cmd.LocalFlags().GetBool("quiet")
+		handleErr(err)
+		paramLogLevel, err := cmd.LocalFlags().GetString("log-level")
+		handleErr(err)
+		paramLogFormat, err := cmd.LocalFlags().GetString("log-format")
+		handleErr(err)
+		paramMerge, err := cmd.LocalFlags().GetStringSlice("merge")
+		handleErr(err)
+		paramSplitByDomain, err := cmd.LocalFlags().GetBool("split-by-domain")
+		handleErr(err)
+		paramOutputDir, err := cmd.LocalFlags().GetString("output-dir")
+		handleErr(err)
+		paramTrimChars, err := cmd.LocalFlags().GetString("trim-chars")
+		handleErr(err)
+		paramNoTrim, err := cmd.LocalFlags().GetBool("no-trim")
+		handleErr(err)
+		paramLeet, err := cmd.LocalFlags().GetBool("leet")
+		handleErr(err)
+		paramLeetMaxSubstitutions, err := cmd.LocalFlags().GetInt("leet-max-substitutions")
+		handleErr(err)
+		paramMangle, err := cmd.LocalFlags().GetBool("mangle")
+		handleErr(err)
+		paramMangleRules, err := cmd.LocalFlags().GetStringSlice("mangle-rules")
+		handleErr(err)
+		paramMaxBodySize, err := cmd.LocalFlags().GetInt("max-body-size")
+		handleErr(err)
+		paramContentTypes, err := cmd.LocalFlags().GetStringSlice("content-types")
+		handleErr(err)
+		paramScopeFile, err := cmd.LocalFlags().GetString("scope-file")
+		handleErr(err)
+		paramWordRegex, err := cmd.LocalFlags().GetString("word-regex")
+		handleErr(err)
+		paramInsecure, err := cmd.LocalFlags().GetBool("insecure")
+		handleErr(err)
+		paramClientCert, err := cmd.LocalFlags().GetString("client-cert")
+		handleErr(err)
+		paramClientKey, err := cmd.LocalFlags().GetString("client-key")
+		handleErr(err)
+		paramCACert, err := cmd.LocalFlags().GetString("ca-cert")
+		handleErr(err)
+		paramAcceptLanguage, err := cmd.LocalFlags().GetString("accept-language")
+		handleErr(err)
+		paramDepthMap, err := cmd.LocalFlags().GetStringArray("depth-map")
+		handleErr(err)
+		paramStateFile, err := cmd.LocalFlags().GetString("state-file")
+		handleErr(err)
+		paramRPS, err := cmd.LocalFlags().GetFloat64("rps")
+		handleErr(err)
+		paramSkipDuplicates, err := cmd.LocalFlags().GetBool("skip-duplicate-bodies")
+		handleErr(err)
+		paramExcludeSelectors, err := cmd.LocalFlags().GetStringArray("exclude-selector")
+		handleErr(err)
+		paramReadability, err := cmd.LocalFlags().GetBool("readability")
+		handleErr(err)
+		paramCacheDir, err := cmd.LocalFlags().GetString("cache-dir")
+		handleErr(err)
+		paramClearCache, err := cmd.LocalFlags().GetBool("clear-cache")
+		handleErr(err)
+		paramJSONL, err := cmd.LocalFlags().GetBool("jsonl")
+		handleErr(err)
+		paramExcludeHidden, err := cmd.LocalFlags().GetBool("exclude-hidden")
+		handleErr(err)
+		paramVisibleOnly, err := cmd.LocalFlags().GetBool("visible-only")
+		handleErr(err)
+		paramRender, err := cmd.LocalFlags().GetBool("render")
+		handleErr(err)
+		paramURLReport, err := cmd.LocalFlags().GetString("url-report")
+		handleErr(err)
+		paramNumbersOutput, err := cmd.LocalFlags().GetString("numbers-output")
+		handleErr(err)
+		// validate params
+		// from here on, errors are caused by the flag values rather than the usage
+		cmd.SilenceUsage = true
+		if paramBasicAuth != "" && !strings.Contains(paramBasicAuth, ":") {
+			return fmt.Errorf("invalid basic auth credentials: must be in the format user:password")
+		}
+		if err := validateHeaders(paramHeaders); err != nil {
+			return err
+		}
+		if err := validateProxies(paramProxies); err != nil {
+			return err
+		}
+		if paramVisibleOnly && (paramIncludeScripts || paramIncludeComments) {
+			return fmt.Errorf("--visible-only can not be combined with --include-scripts or --include-comments")
+		}
+		if paramNoNumeric && paramNumbersOnly {
+			return fmt.Errorf("--no-numeric can not be combined with --numbers-only")
+		}
+		if paramQuiet && (paramDebug || paramProgress || paramSummary) {
+			return fmt.Errorf("--quiet can not be combined with --debug, --progress or --summary")
+		}
+		if (paramDebug || paramQuiet) && cmd.Flags().Changed("log-level") {
+			return fmt.Errorf("--debug and --quiet can not be combined with --log-level")
+		}
+		if paramDebug {
+			paramLogLevel = "debug"
+		}
+		if paramQuiet {
+			paramLogLevel = "error"
+		}
+		if paramJsonOutput && paramCSV {
+			return fmt.Errorf("--json and --csv are mutually exclusive")
+		}
+		if paramJSONL && (paramJsonOutput || paramCSV) {
+			return fmt.Errorf("--jsonl can not be combined with --json or --csv")
+		}
+		if paramStream && (paramJsonOutput || paramCSV) {
+			return fmt.Errorf("--stream can not be combined with --json or --csv")
+		}
+		if paramStream && paramLeet {
+			return fmt.Errorf("--stream can not be combined with --leet")
+		}
+		if paramStream && paramMangle {
+			return fmt.Errorf("--stream can not be combined with --mangle")
+		}
+		var mangleRules []string
+		if paramMangle {
+			mangleRules = paramMangleRules
+		}
+		for _, rule := range mangleRules {
+			if _, ok := skweez.ManglingRules[rule]; !ok {
+				return fmt.Errorf("unknown mangling rule %s", rule)
+			}
+		}
+		if paramRPS < 0 {
+			return fmt.Errorf("--rps must not be negative")
+		}
+		if paramMaxBodySize < 1 {
+			return fmt.Errorf("--max-body-size must be at least 1")
+		}
+		if paramLeetMaxSubstitutions < 1 {
+			return fmt.Errorf("--leet-max-substitutions must be at least 1")
+		}
+		if paramStream && paramMinCount > 1 {
+			return fmt.Errorf("--stream can not be combined with --min-count")
+		}
+		if paramCSV && !cmd.Flags().Changed("sort") {
+			paramSort = "freq"
+		}
+		if paramSort != "alpha" && paramSort != "freq" && paramSort != "none" {
+			return fmt.Errorf("invalid sort order %s: must be alpha, freq or none", paramSort)
+		}
+		wordRegex := skweez.ValidWordRegex
+		if paramUnicode {
+			wordRegex = skweez.ValidUnicodeWordRegex
+		}
+		if paramWordRegex != "" {
+			if paramUnicode {
+				return fmt.Errorf("--word-regex can not be combined with --unicode")
+			}
+			wordRegex, err = regexp.Compile(paramWordRegex)
+			if err != nil {
+				return fmt.Errorf("invalid word regex %s: %w", paramWordRegex, err)
+			}
+		}
+		if paramCase != "preserve" && paramCase != "lower" && paramCase != "upper" {
+			return fmt.Errorf("invalid case %s: must be preserve, lower or upper", paramCase)
+		}
+		stopwords, err := loadStopwords(paramStopwords)
+		if err != nil {
+			return err
+		}
+		var excludeFilters []*regexp.Regexp
+		for _, filter := range paramExcludeURLFilter {
+			excludeFilter, err := regexp.Compile(filter)
+			if err != nil {
+				return fmt.Errorf("invalid exclude url filter %s: %w", filter, err)
+			}
+			excludeFilters = append(excludeFilters, excludeFilter)
+		}
+		emailsOutput := ""
+		if paramEmails {
+			if paramEmailsOutput == "" {
+				return fmt.Errorf("--emails requires an --emails-output file")
+			}
+			if paramEmailsOutput == paramOutput {
+				return fmt.Errorf("--emails-output must differ from --output")
+			}
+			emailsOutput = paramEmailsOutput
+		}
+		if paramURLReport != "" && (paramURLReport == paramOutput || paramURLReport == emailsOutput) {
+			return fmt.Errorf("--url-report must differ from --output and --emails-output")
+		}
+		if paramNumbersOutput != "" && (paramNumbersOutput == paramOutput || paramNumbersOutput == emailsOutput || paramNumbersOutput == paramURLReport) {
+			return fmt.Errorf("--numbers-output must differ from --output, --emails-output and --url-report")
+		}
+		if paramStream && len(paramMerge) > 0 {
+			return fmt.Errorf("--stream can not be combined with --merge")
+		}
+		mergeWords, err := loadWordlists(paramMerge)
+		if err != nil {
+			return err
+		}
+		if paramSplitByDomain {
+			if paramOutputDir == "" {
+				return fmt.Errorf("--split-by-domain requires --output-dir")
+			}
+			if paramOutput != "" || paramStream {
+				return fmt.Errorf("--split-by-domain can not be combined with --output or --stream")
+			}
+		}
+		domainLimits, err := parseLimits(paramLimits)
+		if err != nil {
+			return err
+		}
+		var contentTypes []string
+		if len(paramContentTypes) > 0 {
+			contentTypes = append(slices.Clone(skweez.DefaultContentTypes), paramContentTypes...)
+			for i := range contentTypes {
+				contentTypes[i] = strings.ToLower(strings.TrimSpace(contentTypes[i]))
+			}
+		}
+		for _, selector := range paramExcludeSelectors {
+			if _, err := cascadia.Compile(selector); err != nil {
+				return fmt.Errorf("invalid exclude selector %s: %w", selector, err)
+			}
+		}
+		if paramClearCache && paramCacheDir == "" {
+			return fmt.Errorf("--clear-cache requires --cache-dir")
+		}
+		splitChars := ""
+		if paramSplitPunctuation {
+			splitChars = paramSplitChars
+		}
+		// the args of extract are files, not targets
+		var files []string
+		if cmd == extractCmd {
+			files, args = args, nil
+			if paramSplitByDomain {
+				return fmt.Errorf("extract can not be combined with --split-by-domain, local files have no domain")
+			}
+		}
+		// merge targets from file with unnamed args
+		if paramTargetsFile != "" {
+			fileTargets, err := readLines(paramTargetsFile)
+			if err != nil {
+				return err
+			}
+			args = append(args, fileTargets...)
+		} else if len(args) == 0 && files == nil && stdinIsPiped() {
+			stdinTargets, err := scanLines(os.Stdin)
+			if err != nil {
+				return err
+			}
+			args = append(args, stdinTargets...)
+		}
+		targetDepths, err := parseDepthMap(paramDepthMap)
+		if err != nil {
+			return err
+		}
+		unlimited := paramDepth == 0
+		for target, depth := range targetDepths {
+			args = append(args, target)
+			unlimited = unlimited || depth == 0
+		}
+		if paramDepth < 0 {
+			return fmt.Errorf("invalid depth %d: must be 0 (unlimited) or more", paramDepth)
+		}
+		if err := checkUnlimited(unlimited, files != nil, paramMaxPages, paramUnlimited); err != nil {
+			return err
+		}
 		// sanitize scope param
+		logger, err := newLogger(paramLogLevel, paramLogFormat)
+		if err != nil {
+			return err
+		}
+		slog.SetDefault(logger)
+		if (paramClientCert == "") != (paramClientKey == "") {
+			return fmt.Errorf("--client-cert and --client-key must be given together")
+		}
+		if paramInsecure {
+			logger.Warn("--insecure disables TLS certificate verification, connections can be intercepted")
+		}
+		if paramScopeFile != "" {
+			scopeLines, err := readLines(paramScopeFile)
+			if err != nil {
+				return err
+			}
+			for _, line := range scopeLines {
+				if strings.Contains(line, "/") {
+					logger.Warn("Scope should be a domain without scheme or path", "scope", line, "file", paramScopeFile, "domain", extractDomain(line))
+				}
+			}
+			paramScope = append(paramScope, scopeLines...)
+		}
 		sanitizedScope := []string{}
 		for _, element := range paramScope {
-			sanitizedScope = append(sanitizedScope, extractDomain(element))
+			sanitizedScope = append(sanitizedScope, scopeDomains(element)...)
 		}
 		for _, element := range args {
-			sanitizedScope = append(sanitizedScope, extractDomain(element))
+			sanitizedScope = append(sanitizedScope, scopeDomains(element)...)
 		}
-		if contains(sanitizedScope, "*") {
+		if slices.Contains(sanitizedScope, "*") {
 			// empty string slice as scope -> "unlimited scope"
 			sanitizedScope = []string{}
 		}
-		// process regex filters if any specified
 		var preparedFilters []*regexp.Regexp
+		if paramIncludeSubdomains && len(sanitizedScope) > 0 {
+			// colly's allowed domains are exact matches, so subdomains are allowed via url filters
+			for _, domain := range sanitizedScope {
+				preparedFilters = append(preparedFilters, subdomainFilter(domain))
+			}
+			sanitizedScope = []string{}
+		}
+		// process regex filters if any specified
 		if (paramURLFilter != "") && (strings.Trim(" ", paramURLFilter) != "") {
 			sanitizedScope = []string{} // remove scope limits, so only the filter is applied
-			preparedFilters = append(preparedFilters, regexp.MustCompile(paramURLFilter))
+			preparedFilters = []*regexp.Regexp{regexp.MustCompile(paramURLFilter)}
 		}
 		// collect targets from unnamed args
 		preparedTargets := []string{}
@@ -106,236 +566,960 @@ crawl websites to generate word lists.`,
 			preparedTargets = append(preparedTargets, toUri(element))
 		}
 		config := &skweezConf{
-			debug:      paramDebug,
-			depth:      paramDepth,
-			minLen:     paramMinLen,
-			maxLen:     paramMaxLen,
-			scope:      sanitizedScope,
-			urlFilter:  preparedFilters,
-			output:     paramOutput,
-			noFilter:   paramNoFilter,
-			jsonOutput: paramJsonOutput,
-			targets:    preparedTargets,
-			onlyASCII:  paramOnlyASCII,
-			userAgent:  paramUserAgent,
-			headers:    paramHeaders,
-		}
-		run(config)
+			output:       paramOutput,
+			jsonOutput:   paramJsonOutput,
+			sortMode:     paramSort,
+			withCounts:   paramWithCounts,
+			timeout:      paramTimeout,
+			appendOutput: paramAppend,
+			stream:       paramStream,
+			gzip:         paramGzip || strings.HasSuffix(paramOutput, ".gz"),
+			csvOutput:    paramCSV,
+			jsonCompact:  paramJsonCompact,
+			stdout:       paramStdout,
+			minCount:     paramMinCount,
+			emailsOutput: emailsOutput,
+			dryRun:       paramDryRun,
+			progress:     paramProgress,
+			summary:      paramSummary,
+			outputDir:    paramOutputDir,
+			leet:         paramLeet,
+			leetMax:      paramLeetMaxSubstitutions,
+			mangleRules:  mangleRules,
+			jsonlOutput:  paramJSONL,
+			urlReport:    paramURLReport,
+			numberOutput: paramNumbersOutput,
+			crawler: skweez.Config{
+				Targets:            preparedTargets,
+				Depth:              paramDepth,
+				TargetDepths:       targetDepths,
+				Scope:              sanitizedScope,
+				URLFilters:         preparedFilters,
+				ExternalDepth:      paramExternalDepth,
+				UserAgent:          paramUserAgent,
+				Headers:            paramHeaders,
+				Delay:              paramDelay,
+				RandomDelay:        paramRandomDelay,
+				Parallelism:        paramParallelism,
+				DomainLimits:       domainLimits,
+				IgnoreRobots:       paramIgnoreRobots,
+				UseSitemap:         paramUseSitemap,
+				RequestTimeout:     paramRequestTimeout,
+				Proxies:            paramProxies,
+				MinLen:             paramMinLen,
+				MaxLen:             paramMaxLen,
+				MinUniqueChars:     paramMinUniqueChars,
+				MustContainDigit:   paramMustContainDigit,
+				NoNumeric:          paramNoNumeric,
+				NumbersOnly:        paramNumbersOnly,
+				CollectNumbers:     paramNumbersOutput != "",
+				WordPrefix:         paramWordPrefix,
+				WordSuffix:         paramWordSuffix,
+				IgnoreAffixCase:    paramIgnoreAffixCase,
+				NoFilter:           paramNoFilter,
+				WordRegex:          wordRegex,
+				OnlyASCII:          paramOnlyASCII,
+				IncludeAttrs:       paramIncludeAttrs,
+				IncludeFormFields:  paramIncludeFormFields,
+				NoMeta:             paramNoMeta,
+				Stopwords:          stopwords,
+				Case:               paramCase,
+				Normalize:          paramNormalize,
+				SplitIdentifiers:   paramSplitIdentifiers,
+				MaxPages:           paramMaxPages,
+				BasicAuth:          paramBasicAuth,
+				CookieFile:         paramCookies,
+				Retries:            paramRetries,
+				ExcludeURLFilters:  excludeFilters,
+				IgnoreQueryParams:  paramIgnoreQueryParams,
+				MaxSamePath:        paramMaxSamePath,
+				NoQueryLinks:       paramNoQueryCrawl,
+				AllowRevisit:       paramAllowRevisit,
+				NormalizeURLs:      paramNormalizeURLs,
+				MaxWords:           paramMaxWords,
+				StopAtMaxWords:     paramStopAtMaxWords,
+				Language:           paramLanguage,
+				LanguageConfidence: paramLanguageConfidence,
+				SplitChars:         splitChars,
+				KeepOriginal:       paramKeepOriginal,
+				ExtractEmails:      paramEmails,
+				IncludeScripts:     paramIncludeScripts,
+				IncludeComments:    paramIncludeComments,
+				IncludePDF:         paramIncludePDF,
+				DryRun:             paramDryRun,
+				MergeWords:         mergeWords,
+				SplitByDomain:      paramSplitByDomain,
+				TrimChars:          paramTrimChars,
+				NoTrim:             paramNoTrim,
+				MaxBodySize:        paramMaxBodySize,
+				ContentTypes:       contentTypes,
+				Insecure:           paramInsecure,
+				ClientCert:         paramClientCert,
+				ClientKey:          paramClientKey,
+				CACert:             paramCACert,
+				AcceptLanguage:     paramAcceptLanguage,
+				StateFile:          paramStateFile,
+				RequestsPerSecond:  paramRPS,
+				SkipDuplicates:     paramSkipDuplicates,
+				ExcludeSelectors:   paramExcludeSelectors,
+				Readability:        paramReadability,
+				CacheDir:           paramCacheDir,
+				ExcludeHidden:      paramExcludeHidden,
+				VisibleOnly:        paramVisibleOnly,
+				Render:             paramRender,
+				Logger:             logger,
+			},
+		}
+		// the cache is only cleared once all params are valid
+		if paramClearCache {
+			if err := os.RemoveAll(paramCacheDir); err != nil {
+				return fmt.Errorf("could not clear cache %s: %w", paramCacheDir, err)
+			}
+		}
+		if files != nil {
+			return extractFiles(config, files)
+		}
+		return run(config)
 	},
 }
 
 func Execute() {
-	cobra.CheckErr(rootCmd.Execute())
+	// cobra already printed the error
+	if err := rootCmd.Execute(); err != nil {
+		os.Exit(1)
+	}
 }
 
 func init() {
 	rootCmd.Flags().IntP("depth", "d", 2, "Depth to spider. 0 = unlimited, 1 = Only provided site, 2... = specific depth")
-	rootCmd.Flags().IntP("min-word-length", "m", 3, "Minimum word length")
-	rootCmd.Flags().IntP("max-word-length", "n", 24, "Maximum word length")
+	rootCmd.Flags().IntP("min-word-length", "m", 3, "Minimum word length (inclusive)")
+	rootCmd.Flags().IntP("max-word-length", "n", 24, "Maximum word length (inclusive)")
+	rootCmd.Flags().Int("min-unique-chars", 0, "Minimum number of distinct characters in a word")
+	rootCmd.Flags().Bool("must-contain-digit", false, "Only keep words containing at least one digit")
+	rootCmd.Flags().Bool("no-numeric", false, "Drop words made entirely of digits, like years or IDs")
+	rootCmd.Flags().Bool("numbers-only", false, "Only keep words made entirely of digits, like years or IDs")
+	rootCmd.Flags().String("word-prefix", "", "Only keep words starting with this string, for example admin")
+	rootCmd.Flags().String("word-suffix", "", "Only keep words ending with this string")
+	rootCmd.Flags().Bool("ignore-affix-case", false, "Ignore the case when matching --word-prefix and --word-suffix")
 	rootCmd.Flags().StringSlice("scope", []string{}, "Additional site scope, for example subdomains. If not set, only the provided site's domains are in scope. Using * disables scope checks (careful)")
 	rootCmd.Flags().StringP("output", "o", "", "When set, write an output file")
 	rootCmd.Flags().StringP("url-filter", "u", "", "Filter URL by regexp. .ie: \"(.*\\.)?domain\\.com.*\". Setting this will ignore scope")
+	rootCmd.Flags().Int("external-depth", 0, "Also visit pages outside of the scope up to this many links away from it")
 	rootCmd.Flags().Bool("no-filter", false, "Do not filter out strings that don't match the regex to check if it looks like a valid word (starts and ends with alphanumeric letter, anything else in between). Also ignores --min-word-length and --max-word-length")
-	rootCmd.Flags().Bool("json", false, "Write words + counts in a json file. Requires --output/-o")
-	rootCmd.Flags().Bool("debug", false, "Enable Debug output")
+	rootCmd.Flags().Bool("json", false, "Write words + counts as JSON, to stdout or the file given with --output/-o")
+	rootCmd.Flags().Bool("debug", false, "Enable Debug output, same as --log-level debug")
 	rootCmd.Flags().Bool("onlyascii", false, "When set, filter out non ASCII words")
-	rootCmd.Flags().StringP("user-agent", "a", "", "Set custom user-agent")
-	rootCmd.Flags().StringArray("with-header", []string{}, "Add a header in the format key:value. May be used multiple times to add more headers, for example --with-header 'foo: abc' --with-header 'bar: xyz' to set the headers foo and bar to their appropriate values")
+	rootCmd.Flags().StringP("user-agent", "a", "", "Set custom user-agent. If not set, colly's default user-agent is sent")
+	rootCmd.Flags().Duration("delay", 0, "Delay between requests to the same domain, for example 500ms or 2s")
+	rootCmd.Flags().Duration("random-delay", 0, "Additional random delay up to the given duration that is added to --delay")
+	rootCmd.Flags().IntP("parallelism", "p", 4, "Number of concurrent requests per domain. Higher values crawl faster, lower values are more polite to the target")
+	rootCmd.Flags().StringArray("limit", []string{}, "Delay and parallelism for domains matching a glob in the format glob=delay,parallelism, for example *.example.com=2s,1. May be used multiple times")
+	rootCmd.Flags().StringP("targets-file", "f", "", "Read targets from a file, one URL per line. Blank lines and lines starting with # are ignored")
+	rootCmd.Flags().StringArrayP("with-header", "H", []string{}, "Add a header in the format key:value. May be used multiple times to add more headers, for example --with-header 'foo: abc' --with-header 'bar: xyz' to set the headers foo and bar to their appropriate values. Falls back to a single header in the SKWEEZ_HEADER environment variable")
+	rootCmd.Flags().Bool("ignore-robots", false, "Do not fetch and honor robots.txt of the crawled sites")
+	rootCmd.Flags().Bool("use-sitemap", false, "Additionally seed the crawl with the URLs listed in /sitemap.xml of each target, following sitemap indexes")
+	rootCmd.Flags().Duration("request-timeout", 0, "Timeout for a single request, for example 10s. 0 = colly's default")
+	rootCmd.Flags().Duration("timeout", 0, "Stop crawling after the given duration, for example 30m, and output the words collected so far. 0 = no timeout")
+	rootCmd.Flags().StringSlice("proxy", []string{}, "Route requests through a proxy, for example http://127.0.0.1:8080 or socks5://127.0.0.1:1080. Multiple proxies are rotated round robin. Falls back to the SKWEEZ_PROXY environment variable")
+	rootCmd.Flags().String("sort", "alpha", "Sort order of the plain text output: alpha, freq (most frequent first) or none")
+	rootCmd.Flags().Bool("with-counts", false, "Append the number of occurrences to each word in the plain text output")
+	rootCmd.Flags().Bool("unicode", false, "Treat all unicode letters and digits as valid first and last characters of a word instead of only a-z, A-Z and 0-9")
+	rootCmd.Flags().StringSlice("include-attrs", []string{}, "Also extract words from the values of these HTML attributes. If given without a value, alt, title and aria-label are used")
+	rootCmd.Flags().Bool("include-form-fields", false, "Also extract words from the name, id and placeholder of form fields, like username or old_password")
+	rootCmd.Flags().Bool("no-meta", false, "Do not extract words from the description, keywords and og:* meta tags")
+	rootCmd.Flags().String("stopwords", "", "Filter out stopwords, either from a built-in list (en, de, fr) or from a file with one word per line")
+	rootCmd.Flags().String("case", "preserve", "Normalize the case of words: preserve, lower or upper. Counts of words that only differ in case are merged")
+	rootCmd.Flags().Bool("normalize", false, "Normalize words to Unicode NFC, merging words that only differ in their byte sequence")
+	rootCmd.Flags().Bool("split-identifiers", false, "Additionally split identifiers like getUserName, user_id or HTTPServer into their parts and count those as words, too")
+	rootCmd.Flags().Bool("append", false, "Append to the output file instead of overwriting it")
+	rootCmd.Flags().Bool("stream", false, "Write new words to the output as soon as they are found instead of at the end of the crawl. Words are neither sorted nor counted")
+	rootCmd.Flags().Bool("gzip", false, "Compress the output with gzip. Enabled automatically if the output file ends with .gz")
+	rootCmd.Flags().Bool("csv", false, "Write words + counts as CSV with a word,count header. Sorted by count unless --sort is given")
+	rootCmd.Flags().Bool("json-compact", false, "Write the JSON output in a single line instead of indenting it")
+	rootCmd.Flags().Bool("stdout", false, "Also write the output to stdout when --output is set")
+	rootCmd.Flags().Int("max-pages", 0, "Stop crawling after the given number of pages. 0 = no limit")
+	rootCmd.Flags().Bool("i-know-unlimited", false, "Allow --depth 0 without --max-pages")
+	rootCmd.Flags().String("basic-auth", "", "Credentials for HTTP basic authentication in the format user:password. Only sent to the targets and scope. Falls back to the SKWEEZ_BASIC_AUTH environment variable")
+	// curl users expect --header
+	rootCmd.Flags().SetNormalizeFunc(func(_ *pflag.FlagSet, name string) pflag.NormalizedName {
+		if name == "header" {
+			name = "with-header"
+		}
+		return pflag.NormalizedName(name)
+	})
+	rootCmd.Flags().String("cookies", "", "Load cookies from a file in Netscape format (cookies.txt), for example exported from a browser")
+	rootCmd.Flags().Int("retries", 0, "Retry requests failing with 429, 5xx or connection errors up to the given number of times with exponential backoff")
+	rootCmd.Flags().Bool("include-subdomains", false, "Allow all subdomains of the registrable domains in scope, for example blog.example.com for www.example.com")
+	rootCmd.Flags().StringArray("exclude-url-filter", []string{}, "Do not visit URLs matching this regexp, for example \"/logout|/calendar/\". May be used multiple times. Applies in addition to scope and --url-filter")
+	rootCmd.Flags().StringSlice("ignore-query-params", []string{}, "Remove these query parameters from links before visiting them, for example session IDs")
+	rootCmd.Flags().Int("max-same-path", 0, "Visit each path at most this many times with different query strings, to escape crawler traps. 0 = no limit")
+	rootCmd.Flags().Bool("no-query-crawl", false, "Do not follow links with a query string, like faceted search or pagination. Targets with a query string are still crawled")
+	rootCmd.Flags().Bool("normalize-urls", false, "Remove trailing slashes from links, so /page and /page/ are visited only once")
+	rootCmd.Flags().Bool("allow-revisit", false, "Visit URLs again every time they are linked, which can loop forever, so combine it with --max-pages")
+	rootCmd.Flags().Int("max-words", 0, "Stop collecting new words once the given number of unique words is reached, existing words are still counted. 0 = no limit")
+	rootCmd.Flags().Bool("stop-at-max-words", false, "Stop crawling once --max-words is reached")
+	rootCmd.Flags().Int("min-count", 1, "Only output words found at least this many times")
+	rootCmd.Flags().String("language", "", "Drop text blocks detected as another language, given as ISO 639-1 code like en or de")
+	rootCmd.Flags().Float64("language-confidence", 0.8, "Minimum confidence (0-1) of the language detection to drop a text block")
+	rootCmd.Flags().Bool("split-punctuation", false, "Also split words on punctuation inside of them, for example e-mail into e and mail")
+	rootCmd.Flags().String("split-chars", skweez.DefaultSplitChars, "Characters used as separators by --split-punctuation")
+	rootCmd.Flags().Bool("keep-original", false, "Keep words split by --split-punctuation as a whole, too")
+	rootCmd.Flags().Bool("emails", false, "Also collect email addresses, including mailto: links, and write them to --emails-output")
+	rootCmd.Flags().String("emails-output", "emails.txt", "File to write the email addresses collected by --emails to")
+	rootCmd.Flags().Bool("include-scripts", false, "Also extract string literals and identifiers from inline and linked JavaScript, which is noisy")
+	rootCmd.Flags().Bool("include-comments", false, "Also extract words from HTML comments, which often contain developer notes")
+	rootCmd.Flags().Bool("include-pdf", false, "Also extract words from linked PDF documents, requires a build with -tags pdf")
+	rootCmd.Flags().Bool("dry-run", false, "Only discover and print the URLs that would be crawled, without extracting words")
+	rootCmd.Flags().Bool("progress", false, "Log the number of visited pages, pending requests and unique words every few seconds")
+	rootCmd.Flags().Bool("quiet", false, "Do not log anything to stderr except errors")
+	rootCmd.Flags().String("log-level", "info", "Minimum level of log messages: debug, info, warn or error")
+	rootCmd.Flags().String("log-format", "text", "Format of log messages: text or json")
+	rootCmd.Flags().Bool("summary", false, "Log statistics like the number of pages and words and the most frequent words at the end")
+	rootCmd.Flags().StringSlice("merge", []string{}, "Merge the words of existing word lists, one word per line optionally followed by its count, into the results")
+	rootCmd.Flags().Bool("split-by-domain", false, "Write a separate word list per domain into the directory given by --output-dir")
+	rootCmd.Flags().String("output-dir", "", "Directory for the word lists of --split-by-domain")
+	rootCmd.Flags().String("trim-chars", skweez.DefaultTrimChars, "Characters trimmed from the start and end of words")
+	rootCmd.Flags().Bool("no-trim", false, "Do not trim any characters from the start and end of words")
+	rootCmd.Flags().Bool("leet", false, "Add leetspeak variants of the words like p4ssw0rd to the output")
+	rootCmd.Flags().Int("leet-max-substitutions", 2, "Maximum number of characters replaced in a single leetspeak variant")
+	rootCmd.Flags().Bool("mangle", false, "Add variants of the words generated by the mangling rules of --mangle-rules")
+	rootCmd.Flags().StringSlice("mangle-rules", skweez.DefaultManglingRules, "Mangling rules applied by --mangle: capitalize, upper, append-digit, append-year")
+	rootCmd.Flags().Int("max-body-size", 10*1024*1024, "Maximum size of a response body in bytes, larger responses are truncated")
+	rootCmd.Flags().StringSlice("content-types", []string{}, "Additional Content-Types to extract words from, for example text/plain. text/html and application/xhtml+xml are always processed")
+	rootCmd.Flags().String("scope-file", "", "File with additional site scope, one domain per line")
+	rootCmd.Flags().String("word-regex", "", "Only keep words matching this regexp instead of the default word filter, for example \"^[a-z]{4,}$\". Word lengths still apply")
+	rootCmd.Flags().Bool("insecure", false, "Do not verify TLS certificates, for example of staging sites with self-signed certificates")
+	rootCmd.Flags().String("client-cert", "", "PEM client certificate for TLS client authentication, requires --client-key")
+	rootCmd.Flags().String("client-key", "", "PEM private key of --client-cert")
+	rootCmd.Flags().String("ca-cert", "", "PEM certificates of private CAs to trust instead of the system CAs")
+	rootCmd.Flags().String("accept-language", "", "Accept-Language header to request localized content, for example \"de-DE,de;q=0.9\"")
+	rootCmd.Flags().StringArray("depth-map", []string{}, "Crawl a target with its own depth instead of --depth, in the format url=depth. May be used multiple times")
+	rootCmd.Flags().String("state-file", "", "Save the visited URLs and words to this file regularly and resume the crawl from it if it exists")
+	rootCmd.Flags().Float64("rps", 0, "Maximum number of requests per second across all domains. 0 = no limit")
+	rootCmd.Flags().Bool("skip-duplicate-bodies", false, "Do not extract words from pages with exactly the same content as a previous page")
+	rootCmd.Flags().StringArray("exclude-selector", []string{}, "Do not extract words from elements matching this CSS selector, for example \"nav,footer,.sidebar\". May be used multiple times")
+	rootCmd.Flags().Bool("readability", false, "Only extract words from the main content of pages like the text of articles, leaving out menus and ads")
+	rootCmd.Flags().String("cache-dir", "", "Cache responses in this directory and reuse them in later runs instead of downloading them again")
+	rootCmd.Flags().Bool("clear-cache", false, "Delete the contents of --cache-dir before crawling")
+	rootCmd.Flags().Bool("jsonl", false, "Write one JSON object per word and line like {\"word\":\"skweez\",\"count\":3}, also works with --stream")
+	rootCmd.Flags().Bool("exclude-hidden", false, "Do not extract words from elements hidden by a hidden attribute or an inline display:none or visibility:hidden style")
+	rootCmd.Flags().Bool("visible-only", false, "Only extract words from elements rendered visibly, skipping the head including meta tags as well as script, style, noscript and template elements")
+	rootCmd.Flags().Bool("render", false, "Render pages in headless Chrome to extract words and links added by JavaScript. Slow, requires a build with -tags render and Chrome")
+	rootCmd.Flags().String("url-report", "", "File to write the status code and content type of every visited URL to, as JSON if it ends in .json, otherwise as CSV")
+	rootCmd.Flags().String("config", "", "Config file setting the defaults of all flags, keyed by their long names (default ~/.skweez.yaml)")
+	rootCmd.Flags().String("numbers-output", "", "Also collect words made entirely of digits, like years or IDs, and write them to this file, regardless of --no-numeric")
+	rootCmd.Flags().Lookup("include-attrs").NoOptDefVal = "alt,title,aria-label"
+	// extract shares all flags and the RunE of rootCmd
+	extractCmd.Flags().AddFlagSet(rootCmd.Flags())
+	extractCmd.RunE = rootCmd.RunE
+	rootCmd.AddCommand(extractCmd)
+}
+
+// newLogger creates the logger of the crawl, writing messages of at least the given level to stderr
+func newLogger(level string, format string) (*slog.Logger, error) {
+	var minLevel slog.Level
+	if err := minLevel.UnmarshalText([]byte(level)); err != nil {
+		return nil, fmt.Errorf("invalid log level %s: %w", level, err)
+	}
+	options := slog.HandlerOptions{Level: minLevel}
+	switch format {
+	case "text":
+		return slog.New(options.NewTextHandler(os.Stderr)), nil
+	case "json":
+		return slog.New(options.NewJSONHandler(os.Stderr)), nil
+	default:
+		return nil, fmt.Errorf("invalid log format %s: must be text or json", format)
+	}
+}
+
+// handleErr logs an error, for example of a flag getter
+func handleErr(err error) {
+	if err != nil {
+		slog.Error(err.Error())
+	}
 }
 
-func handleErr(err error, critical bool) {
+// readLines returns the trimmed lines of a file, skipping blank lines and # comments
+func readLines(path string) ([]string, error) {
+	filedescriptor, err := os.Open(path)
 	if err != nil {
-		if critical {
-			panic(err.Error())
-		} else {
-			fmt.Println(err.Error())
+		return nil, fmt.Errorf("could not open %s: %w", path, err)
+	}
+	defer filedescriptor.Close()
+	return scanLines(filedescriptor)
+}
+
+// scanLines is like readLines, but reads from an arbitrary reader
+func scanLines(reader io.Reader) ([]string, error) {
+	lines := []string{}
+	scanner := bufio.NewScanner(reader)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
 		}
+		lines = append(lines, line)
 	}
+	return lines, scanner.Err()
 }
 
-// https://play.golang.org/p/Qg_uv_inCek
-// contains checks if a string is present in a slice
-func contains(s []string, str string) bool {
-	for _, v := range s {
-		if v == str {
-			return true
+// loadWordlists reads and merges word lists with one word per line, optionally followed by its count
+// like in the output of --with-counts. Counts of words found in several lists are summed up.
+func loadWordlists(paths []string) (map[string]int, error) {
+	if len(paths) == 0 {
+		return nil, nil
+	}
+	words := make(map[string]int)
+	for _, path := range paths {
+		lines, err := readLines(path)
+		if err != nil {
+			return nil, err
+		}
+		for _, line := range lines {
+			fields := strings.Fields(line)
+			count := 1
+			if len(fields) == 2 {
+				if parsed, err := strconv.Atoi(fields[1]); err == nil && parsed > 0 {
+					count = parsed
+				}
+			}
+			words[fields[0]] += count
 		}
 	}
+	return words, nil
+}
 
-	return false
+// parseLimits parses the delay and parallelism of domains in the format glob=delay,parallelism
+func parseLimits(specs []string) ([]skweez.DomainLimit, error) {
+	var limits []skweez.DomainLimit
+	for _, spec := range specs {
+		domainGlob, values, found := strings.Cut(spec, "=")
+		delay, parallelism, hasParallelism := strings.Cut(values, ",")
+		if !found || !hasParallelism || domainGlob == "" {
+			return nil, fmt.Errorf("invalid limit %s: must be in the format glob=delay,parallelism", spec)
+		}
+		if _, err := glob.Compile(domainGlob); err != nil {
+			return nil, fmt.Errorf("invalid limit %s: %w", spec, err)
+		}
+		limit := skweez.DomainLimit{DomainGlob: domainGlob}
+		var err error
+		if limit.Delay, err = time.ParseDuration(delay); err != nil || limit.Delay < 0 {
+			return nil, fmt.Errorf("invalid limit %s: delay must be a duration like 500ms or 2s", spec)
+		}
+		if limit.Parallelism, err = strconv.Atoi(parallelism); err != nil || limit.Parallelism < 1 {
+			return nil, fmt.Errorf("invalid limit %s: parallelism must be a number of at least 1", spec)
+		}
+		limits = append(limits, limit)
+	}
+	return limits, nil
 }
 
-func initColly(config *skweezConf) *colly.Collector {
-	c := colly.NewCollector(
-		colly.MaxDepth(config.depth),
-		colly.AllowedDomains(config.scope...),
-		colly.URLFilters(config.urlFilter...),
-	)
-	if config.userAgent != "" {
-		c.UserAgent = config.userAgent
+// parseDepthMap parses targets with their own depth in the format url=depth
+func parseDepthMap(entries []string) (map[string]int, error) {
+	if len(entries) == 0 {
+		return nil, nil
 	}
-	c.AllowURLRevisit = false
-	return c
+	depths := make(map[string]int, len(entries))
+	for _, entry := range entries {
+		// URLs may contain = in their query, the depth is after the last one
+		separator := strings.LastIndex(entry, "=")
+		if separator < 1 {
+			return nil, fmt.Errorf("invalid depth map entry %s: must be in the format url=depth", entry)
+		}
+		depth, err := strconv.Atoi(entry[separator+1:])
+		if err != nil || depth < 0 {
+			return nil, fmt.Errorf("invalid depth map entry %s: depth must be a number of at least 0", entry)
+		}
+		depths[toUri(entry[:separator])] = depth
+	}
+	return depths, nil
 }
 
-func registerCallbacks(collector *colly.Collector, config *skweezConf, cache *map[string]int) {
-	logger := log.New(os.Stderr, "", log.Ltime)
+// checkUnlimited refuses crawls of unlimited depth without a page limit unless confirmed,
+// as an unlimited crawl with a large scope easily runs forever. Local files are never crawled.
+func checkUnlimited(unlimited bool, hasFiles bool, maxPages int, confirmed bool) error {
+	if unlimited && !hasFiles && maxPages == 0 && !confirmed {
+		return fmt.Errorf("depth 0 crawls without limit, set --max-pages or confirm with --i-know-unlimited")
+	}
+	return nil
+}
 
-	collector.OnHTML("a[href]", func(e *colly.HTMLElement) {
-		e.Request.Visit(e.Attr("href"))
-	})
+// validateHeaders checks that all headers are in the format key:value with a non-empty key
+func validateHeaders(headers []string) error {
+	for _, header := range headers {
+		headerSplit := strings.SplitN(header, ":", 2)
+		if len(headerSplit) < 2 || strings.TrimSpace(headerSplit[0]) == "" {
+			return fmt.Errorf("invalid header %s: must be in the format key:value", header)
+		}
+	}
+	return nil
+}
 
-	collector.OnRequest(func(r *colly.Request) {
-		if len(config.headers) > 0 {
-			for _, header := range config.headers {
-				var headerSplit = strings.SplitN(header, ":", 2)
-				if len(headerSplit) > 1 {
-					// header needs to be trimmed otherwise colly wont send request
-					r.Headers.Set(strings.TrimSpace(headerSplit[0]), headerSplit[1])
-				}
-			}
+// validateProxies checks that all proxies are URLs with a supported scheme and a host
+func validateProxies(proxies []string) error {
+	for _, proxyURL := range proxies {
+		parsed, err := url.Parse(proxyURL)
+		if err != nil {
+			return fmt.Errorf("invalid proxy %s: %w", proxyURL, err)
 		}
-		if config.debug {
-			logger.Println("Visiting", r.URL)
+		if parsed.Scheme != "http" && parsed.Scheme != "https" && parsed.Scheme != "socks5" {
+			return fmt.Errorf("invalid proxy %s: scheme must be http, https or socks5", proxyURL)
 		}
-	})
-
-	collector.OnError(func(_ *colly.Response, err error) {
-		if config.debug {
-			logger.Println("Something went wrong:", err)
+		if parsed.Host == "" {
+			return fmt.Errorf("invalid proxy %s: missing host", proxyURL)
 		}
-	})
+	}
+	return nil
+}
+
+// loadStopwords returns the lowercased stopwords of a built-in list or a file
+func loadStopwords(listOrPath string) (map[string]bool, error) {
+	stopwords := map[string]bool{}
+	if listOrPath == "" {
+		return stopwords, nil
+	}
+	var lines []string
+	builtin, err := builtinStopwords.ReadFile("stopwords/" + listOrPath + ".txt")
+	if err == nil {
+		lines, err = scanLines(bytes.NewReader(builtin))
+	} else {
+		lines, err = readLines(listOrPath)
+	}
+	if err != nil {
+		return nil, err
+	}
+	for _, word := range lines {
+		stopwords[strings.ToLower(word)] = true
+	}
+	return stopwords, nil
+}
+
+// stdinIsPiped checks whether stdin is a pipe or file rather than a terminal
+func stdinIsPiped() bool {
+	stat, err := os.Stdin.Stat()
+	if err != nil {
+		return false
+	}
+	return stat.Mode()&os.ModeCharDevice == 0
+}
+
+func run(config *skweezConf) error {
+	ctx := context.Background()
+	if config.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, config.timeout)
+		defer cancel()
+	}
+	ctx, stop := interruptible(ctx, config.crawler.Logger)
+	defer stop()
+	if config.dryRun {
+		return listURLs(ctx, config)
+	}
+	if config.stream {
+		return streamResults(ctx, config)
+	}
+	start := time.Now()
+	report := watchURLs(config)
+	crawler, stopProgress := newCrawler(config)
+	words, err := crawler.Run(ctx)
+	stopProgress()
+	if err != nil {
+		return err
+	}
+	if ctx.Err() == context.DeadlineExceeded {
+		config.crawler.Logger.Warn("Timeout reached, results are incomplete")
+	}
+	if len(words) == 0 {
+		diagnoseEmpty(config.crawler.Logger, crawler.Progress())
+	}
+	if err := outputEmails(config, crawler.Emails()); err != nil {
+		return err
+	}
+	if err := outputNumbers(config, crawler.Numbers()); err != nil {
+		return err
+	}
+	if err := report.write(config.urlReport); err != nil {
+		return err
+	}
+	if config.crawler.SplitByDomain {
+		err = outputDomains(config, crawler.DomainWords())
+	} else {
+		err = outputResults(config, words)
+	}
+	if err != nil {
+		return err
+	}
+	if config.summary {
+		logSummary(config.crawler.Logger, crawler.Progress().Pages, words, time.Since(start))
+	}
+	return nil
+}
 
-	collector.OnResponse(func(r *colly.Response) {
-		if config.debug {
-			logger.Println("Visited", r.Request.URL)
+// interruptible returns a context that is canceled on SIGINT or SIGTERM, so the crawl stops and the words
+// collected so far are written. Another signal terminates skweez immediately.
+func interruptible(parent context.Context, logger *slog.Logger) (context.Context, func()) {
+	ctx, cancel := context.WithCancel(parent)
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		select {
+		case <-signals:
+			logger.Warn("Interrupted, writing the words collected so far. Interrupt again to exit immediately")
+			// restore the default behavior for the next signal
+			signal.Stop(signals)
+			cancel()
+		case <-ctx.Done():
 		}
-	})
+	}()
+	return ctx, func() {
+		signal.Stop(signals)
+		cancel()
+	}
+}
 
-	collector.OnScraped(func(r *colly.Response) {
-		// https://stackoverflow.com/questions/44441665/how-to-extract-only-text-from-html-in-golang
-		logger.Println("Finished", r.Request.URL)
+// diagnoseEmpty logs possible causes of a crawl without any words along with the status codes of the responses
+func diagnoseEmpty(logger *slog.Logger, progress skweez.Progress) {
+	if progress.Pages == 0 {
+		logger.Warn("No words found, no page could be fetched. Check robots.txt (see --ignore-robots), the scope "+
+			"and the connection to the targets", "status_codes", progress.StatusCodes)
+		return
+	}
+	logger.Warn("No words found. The site may block the user agent (try --user-agent), render its content with JavaScript "+
+		"or serve another Content-Type than HTML (see --content-types)", "pages", progress.Pages, "status_codes", progress.StatusCodes)
+}
 
-		extractWords(r.Body, config, cache)
-	})
+// summaryTopWords is the number of most frequent words listed by --summary
+const summaryTopWords = 10
+
+// logSummary logs statistics about the crawl
+func logSummary(logger *slog.Logger, pages int, words map[string]int, elapsed time.Duration) {
+	total := 0
+	for _, count := range words {
+		total += count
+	}
+	logger.Info("Summary", "pages", pages, "words", total, "unique_words", len(words), "elapsed", elapsed.Round(time.Millisecond))
+	top := sortedWords(words, "freq")
+	if len(top) > summaryTopWords {
+		top = top[:summaryTopWords]
+	}
+	for i, word := range top {
+		logger.Info("Top word", "rank", i+1, "word", word, "count", words[word])
+	}
 }
 
-func Split(r rune) bool {
-	return r == ' ' || r == '\n' || r == '\r'
-}
-
-// cache should be a param, too. Allows for better testability
-func extractWords(body []byte, config *skweezConf, cache *map[string]int) {
-	domDoc := html.NewTokenizer(strings.NewReader(string(body)))
-	previousStartTokenTest := domDoc.Token()
-outer:
-	for {
-		tt := domDoc.Next()
-		switch {
-		case tt == html.ErrorToken:
-			break outer
-		case tt == html.StartTagToken:
-			previousStartTokenTest = domDoc.Token()
-		case tt == html.TextToken:
-			if previousStartTokenTest.Data == "script" || previousStartTokenTest.Data == "style" {
-				continue
-			}
-			TxtContent := strings.TrimSpace(html.UnescapeString(string(domDoc.Text())))
-			if len(TxtContent) > 0 {
-				unfilteredWords := strings.FieldsFunc(TxtContent, Split)
-				var filteredWords []string
-				for _, word := range unfilteredWords {
-					candidate := strings.Trim(word, stripTrailingSymbols)
-					if config.noFilter {
-						filteredWords = append(filteredWords, candidate)
-					} else {
-						if validWordRegex.MatchString(candidate) {
-							if len(candidate) > config.minLen && len(candidate) < config.maxLen && allPrintable(word) {
-								if config.onlyASCII {
-									candidate := utf8string.NewString(word)
-									if !candidate.IsASCII() {
-										continue
-									}
-								}
-								filteredWords = append(filteredWords, candidate)
-							}
-						}
-					}
-				}
-				for _, word := range filteredWords {
-					(*cache)[word] += 1
-				}
+// progressInterval is the time between two progress reports of --progress
+const progressInterval = 5 * time.Second
+
+// reportProgress logs the progress of the crawler until the returned stop function is called
+func reportProgress(crawler *skweez.Crawler, logger *slog.Logger) (stop func()) {
+	done := make(chan struct{})
+	start := time.Now()
+	go func() {
+		ticker := time.NewTicker(progressInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				progress := crawler.Progress()
+				logger.Info("Progress", "pages", progress.Pages, "pending", progress.Pending,
+					"unique_words", progress.Words, "elapsed", time.Since(start).Round(time.Second))
 			}
 		}
+	}()
+	return func() { close(done) }
+}
+
+// newCrawler creates the crawler, reporting its progress if requested.
+// The returned stop function ends the progress reports.
+func newCrawler(config *skweezConf) (*skweez.Crawler, func()) {
+	crawler := skweez.NewCrawler(config.crawler)
+	if !config.progress {
+		return crawler, func() {}
 	}
+	return crawler, reportProgress(crawler, config.crawler.Logger)
 }
 
-func run(config *skweezConf) {
-	cache := make(map[string]int)
-	c := initColly(config)
-	registerCallbacks(c, config, &cache)
+// listURLs runs the crawler without extracting words and writes the URLs of the visited pages to the output
+func listURLs(ctx context.Context, config *skweezConf) (err error) {
+	output, err := openOutput(config)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if closeErr := output.Close(); err == nil {
+			err = closeErr
+		}
+	}()
+	config.crawler.OnPage = func(url string) {
+		output.WriteString(url + "\n")
+	}
+	report := watchURLs(config)
+	crawler, stopProgress := newCrawler(config)
+	_, err = crawler.Run(ctx)
+	stopProgress()
+	if err != nil {
+		return err
+	}
+	if ctx.Err() == context.DeadlineExceeded {
+		config.crawler.Logger.Warn("Timeout reached, results are incomplete")
+	}
+	return report.write(config.urlReport)
+}
 
-	for _, toVisit := range config.targets {
-		c.Visit(toVisit)
+// streamResults runs the crawler and writes new words to the output after every page
+func streamResults(ctx context.Context, config *skweezConf) (err error) {
+	output, err := openOutput(config)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if closeErr := output.Close(); err == nil {
+			err = closeErr
+		}
+	}()
+	stream, ok := newWordWriter(config, output).(streamWriter)
+	if !ok {
+		return fmt.Errorf("the output format does not support --stream")
+	}
+	var writeErr error
+	config.crawler.OnNewWords = func(words []string) {
+		err := stream.WriteStream(words)
+		if err == nil {
+			err = output.Flush()
+		}
+		if err != nil && writeErr == nil {
+			writeErr = err
+		}
+	}
+	start := time.Now()
+	report := watchURLs(config)
+	crawler, stopProgress := newCrawler(config)
+	words, err := crawler.Run(ctx)
+	stopProgress()
+	if err != nil {
+		return err
 	}
-	outputResults(config, cache)
+	if ctx.Err() == context.DeadlineExceeded {
+		config.crawler.Logger.Warn("Timeout reached, results are incomplete")
+	}
+	if len(words) == 0 {
+		diagnoseEmpty(config.crawler.Logger, crawler.Progress())
+	}
+	if err := outputEmails(config, crawler.Emails()); err != nil {
+		return err
+	}
+	if err := outputNumbers(config, crawler.Numbers()); err != nil {
+		return err
+	}
+	if err := report.write(config.urlReport); err != nil {
+		return err
+	}
+	if config.summary {
+		logSummary(config.crawler.Logger, crawler.Progress().Pages, words, time.Since(start))
+	}
+	return writeErr
+}
+
+// outputEmails writes the collected email addresses to their own file, one per line
+func outputEmails(config *skweezConf, emails []string) error {
+	return writeLines(config.emailsOutput, emails)
+}
+
+// outputNumbers writes the collected numbers to their own file, one per line
+func outputNumbers(config *skweezConf, numbers []string) error {
+	return writeLines(config.numberOutput, numbers)
+}
 
+// writeLines writes the lines to a file, an empty path writes nothing
+func writeLines(path string, lines []string) error {
+	if path == "" {
+		return nil
+	}
+	var builder strings.Builder
+	for _, line := range lines {
+		builder.WriteString(line + "\n")
+	}
+	return os.WriteFile(path, []byte(builder.String()), 0644)
 }
 
-func outputResults(config *skweezConf, cache map[string]int) {
-	if config.jsonOutput {
-		jsonString, err := json.Marshal(cache)
-		handleErr(err, false)
-		if config.output != "" {
-			mode := os.O_RDWR | os.O_CREATE
-			filedescriptor, err := os.OpenFile(config.output, mode, 0644)
-			handleErr(err, true)
-			defer filedescriptor.Close()
-			filedescriptor.Write(jsonString)
-		} else {
-			fmt.Println(string(jsonString[:]))
+// domainFileName replaces the characters of hosts with port or IPv6 addresses that are not allowed in file names
+var domainFileName = strings.NewReplacer(":", "_", "[", "", "]", "")
+
+// outputDomains writes one file per domain into the output directory, in the format of outputResults
+func outputDomains(config *skweezConf, domainWords map[string]map[string]int) error {
+	if err := os.MkdirAll(config.outputDir, 0755); err != nil {
+		return err
+	}
+	extension := ".txt"
+	switch {
+	case config.jsonOutput:
+		extension = ".json"
+	case config.csvOutput:
+		extension = ".csv"
+	case config.jsonlOutput:
+		extension = ".jsonl"
+	}
+	if config.gzip {
+		extension += ".gz"
+	}
+	for domain, words := range domainWords {
+		domainConfig := *config
+		domainConfig.output = filepath.Join(config.outputDir, domainFileName.Replace(domain)+extension)
+		if err := outputResults(&domainConfig, words); err != nil {
+			return err
 		}
-	} else {
-		if config.output != "" {
-			mode := os.O_RDWR | os.O_CREATE
-			filedescriptor, err := os.OpenFile(config.output, mode, 0644)
-			handleErr(err, true)
-			defer filedescriptor.Close()
-			for word := range cache {
-				filedescriptor.WriteString(fmt.Sprintf("%s\n", word))
-			}
-		} else {
-			for word := range cache {
-				fmt.Printf("%s\n", word)
+	}
+	return nil
+}
+
+func outputResults(config *skweezConf, cache map[string]int) (err error) {
+	if config.minCount > 1 {
+		cache = filterByCount(cache, config.minCount)
+	}
+	if config.leet {
+		cache = expandWords(cache, func(word string) []string {
+			return skweez.LeetVariants(word, config.leetMax)
+		})
+	}
+	if len(config.mangleRules) > 0 {
+		cache = expandWords(cache, func(word string) []string {
+			return skweez.Mangle(word, config.mangleRules)
+		})
+	}
+	output, err := openOutput(config)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if closeErr := output.Close(); err == nil {
+			err = closeErr
+		}
+	}()
+	return newWordWriter(config, output).WriteWords(cache)
+}
+
+// outputWriter buffers writes to the output file or stdout, optionally gzip compressed
+type outputWriter struct {
+	*bufio.Writer
+	gzipWriter *gzip.Writer
+	file       *os.File
+}
+
+// isStream checks if a path is a named pipe, a device or stdout, like /dev/stdout.
+// Truncating /dev/stdout would truncate the file stdout is redirected to.
+func isStream(path string) bool {
+	info, err := os.Stat(path)
+	if err != nil {
+		return false
+	}
+	if !info.Mode().IsRegular() {
+		return true
+	}
+	stdout, err := os.Stdout.Stat()
+	return err == nil && os.SameFile(info, stdout)
+}
+
+// openOutput opens the output file, truncating it unless appending is requested.
+// Without an output file, stdout is used.
+func openOutput(config *skweezConf) (*outputWriter, error) {
+	output := &outputWriter{}
+	var writer io.Writer = os.Stdout
+	if config.output != "" {
+		mode := os.O_WRONLY | os.O_CREATE | os.O_TRUNC
+		if config.appendOutput {
+			mode = os.O_WRONLY | os.O_CREATE | os.O_APPEND
+		}
+		if isStream(config.output) {
+			mode = os.O_WRONLY | os.O_APPEND
+		}
+		filedescriptor, err := os.OpenFile(config.output, mode, 0644)
+		if err != nil {
+			return nil, err
+		}
+		output.file = filedescriptor
+		writer = filedescriptor
+		if config.stdout {
+			writer = io.MultiWriter(filedescriptor, os.Stdout)
+		}
+	}
+	if config.gzip {
+		output.gzipWriter = gzip.NewWriter(writer)
+		writer = output.gzipWriter
+	}
+	output.Writer = bufio.NewWriter(writer)
+	return output, nil
+}
+
+// Flush writes buffered data through to the underlying file
+func (output *outputWriter) Flush() error {
+	if err := output.Writer.Flush(); err != nil {
+		return err
+	}
+	if output.gzipWriter != nil {
+		return output.gzipWriter.Flush()
+	}
+	return nil
+}
+
+// Close flushes all data and closes the output file, stdout is left open
+func (output *outputWriter) Close() error {
+	err := output.Writer.Flush()
+	if output.gzipWriter != nil {
+		if closeErr := output.gzipWriter.Close(); err == nil {
+			err = closeErr
+		}
+	}
+	if output.file != nil {
+		if closeErr := output.file.Close(); err == nil {
+			err = closeErr
+		}
+	}
+	return err
+}
+
+// filterByCount returns the words of the cache occurring at least minCount times
+func filterByCount(cache map[string]int, minCount int) map[string]int {
+	filtered := make(map[string]int)
+	for word, count := range cache {
+		if count >= minCount {
+			filtered[word] = count
+		}
+	}
+	return filtered
+}
+
+// expandWords returns the words along with the variants generated by variantsOf.
+// Variants get the count of the word they are derived from, unless they were found themselves.
+func expandWords(cache map[string]int, variantsOf func(word string) []string) map[string]int {
+	expanded := make(map[string]int, len(cache))
+	for word, count := range cache {
+		expanded[word] = count
+	}
+	for word, count := range cache {
+		for _, variant := range variantsOf(word) {
+			if _, found := cache[variant]; !found && expanded[variant] < count {
+				expanded[variant] = count
 			}
 		}
 	}
+	return expanded
 }
 
+// sortedWords returns the words of the cache in the given sort order
+func sortedWords(cache map[string]int, sortMode string) []string {
+	words := make([]string, 0, len(cache))
+	for word := range cache {
+		words = append(words, word)
+	}
+	switch sortMode {
+	case "alpha":
+		sort.Strings(words)
+	case "freq":
+		// most frequent first, ties are broken alphabetically
+		sort.Slice(words, func(i, j int) bool {
+			if cache[words[i]] != cache[words[j]] {
+				return cache[words[i]] > cache[words[j]]
+			}
+			return words[i] < words[j]
+		})
+	}
+	return words
+}
+
+// subdomainFilter returns a regexp matching URLs of the registrable domain of a host and all its subdomains
+func subdomainFilter(domain string) *regexp.Regexp {
+	host, port, err := net.SplitHostPort(domain)
+	if err != nil {
+		host, port = domain, ""
+	}
+	// IP addresses and unknown suffixes have no registrable domain, keep the host as it is
+	if registrable, err := publicsuffix.EffectiveTLDPlusOne(host); err == nil {
+		host = registrable
+	}
+	portPattern := `(:\d+)?`
+	if port != "" {
+		portPattern = ":" + port
+	}
+	return regexp.MustCompile(`^https?://([^/?#@]+\.)?` + regexp.QuoteMeta(host) + portPattern + `([/?#]|$)`)
+}
+
+// extractDomain returns the bare host of a URL or domain, without scheme, userinfo, port and path
+// https://user:pw@github.com:443/edermi/skweez -> github.com
 func extractDomain(uri string) string {
-	if !strings.Contains(uri, "/") {
+	parsed, err := parseHost(uri)
+	if err != nil {
 		return uri
-	} else {
-		// Strip https://, http:// and everything after and including the first slash of the remaining string
-		// https://github.com/edermi/skweez -> github.com/edermi/skweez -> github.com
-		noProto := strings.TrimPrefix(strings.TrimPrefix(uri, "http://"), "https://")
-		return strings.Split(noProto, "/")[0]
 	}
+	return parsed.Hostname()
 }
 
-func toUri(domain string) string {
-	if strings.HasPrefix(domain, "http://") || strings.HasPrefix(domain, "https://") {
-		return domain
+// scopeDomains returns the scope entries of a URL or domain. colly matches the allowed domains against the
+// host including the port and the brackets of IPv6 addresses, so such hosts are in scope as well.
+func scopeDomains(uri string) []string {
+	parsed, err := parseHost(uri)
+	if err != nil {
+		return []string{uri}
+	}
+	if parsed.Host == parsed.Hostname() {
+		return []string{parsed.Hostname()}
+	}
+	return []string{parsed.Hostname(), parsed.Host}
+}
+
+// parseHost parses a URL, which may also be a plain domain without scheme
+func parseHost(uri string) (*url.URL, error) {
+	if !strings.Contains(uri, "://") {
+		uri = "//" + uri
+	}
+	parsed, err := url.Parse(uri)
+	if err == nil && parsed.Host == "" {
+		err = fmt.Errorf("missing host in %s", uri)
+	}
+	if err == nil {
+		punycodeHost(parsed)
+	}
+	return parsed, err
+}
+
+// punycodeHost converts an internationalized host like müller.de to its ASCII form xn--mller-kva.de,
+// which is how it appears in links and is matched against the scope
+func punycodeHost(parsed *url.URL) {
+	hostname := parsed.Hostname()
+	if isASCII(hostname) {
+		return
+	}
+	ascii, err := idna.Lookup.ToASCII(hostname)
+	if err != nil {
+		return
+	}
+	if port := parsed.Port(); port != "" {
+		parsed.Host = net.JoinHostPort(ascii, port)
 	} else {
-		return "https://" + domain
+		parsed.Host = ascii
 	}
 }
 
-func allPrintable(word string) bool {
-	for _, rune := range word {
-		if !unicode.IsPrint(rune) {
+// isASCII checks whether a string only consists of ASCII characters
+func isASCII(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] >= utf8.RuneSelf {
 			return false
 		}
 	}
 	return true
 }
+
+func toUri(domain string) string {
+	uri := domain
+	if !strings.HasPrefix(domain, "http://") && !strings.HasPrefix(domain, "https://") {
+		uri = "https://" + domain
+	}
+	parsed, err := url.Parse(uri)
+	if err != nil || isASCII(parsed.Hostname()) {
+		return uri
+	}
+	punycodeHost(parsed)
+	return parsed.String()
+}
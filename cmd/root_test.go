@@ -0,0 +1,255 @@
+/*
+Copyright © 2021 Michael Eder @edermi / twitter.com/michael_eder_
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package cmd
+
+import (
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// readFile returns the content of a file written by a test
+func readFile(t *testing.T, path string) string {
+	t.Helper()
+	content, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(content)
+}
+
+func TestOutputResultsOverwrite(t *testing.T) {
+	config := &skweezConf{output: filepath.Join(t.TempDir(), "words.txt"), sortMode: "alpha"}
+	if err := outputResults(config, map[string]int{"password": 1, "secret": 1, "summer": 1}); err != nil {
+		t.Fatal(err)
+	}
+	// a shorter result must not leave the tail of the previous one behind
+	if err := outputResults(config, map[string]int{"abc": 1}); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := readFile(t, config.output), "abc\n"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestOutputResultsAppend(t *testing.T) {
+	config := &skweezConf{output: filepath.Join(t.TempDir(), "words.txt"), sortMode: "alpha", appendOutput: true}
+	if err := outputResults(config, map[string]int{"password": 1, "secret": 1}); err != nil {
+		t.Fatal(err)
+	}
+	if err := outputResults(config, map[string]int{"abc": 1}); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := readFile(t, config.output), "password\nsecret\nabc\n"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestOutputResultsGzip(t *testing.T) {
+	config := &skweezConf{output: filepath.Join(t.TempDir(), "words.txt.gz"), sortMode: "alpha", gzip: true}
+	if err := outputResults(config, map[string]int{"password": 1, "secret": 2}); err != nil {
+		t.Fatal(err)
+	}
+	file, err := os.Open(config.output)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	reader, err := gzip.NewReader(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	content, err := io.ReadAll(reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := string(content), "password\nsecret\n"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+// captureStdout returns everything written to stdout while run is called
+func captureStdout(t *testing.T, run func()) string {
+	t.Helper()
+	reader, writer, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stdout := os.Stdout
+	os.Stdout = writer
+	defer func() { os.Stdout = stdout }()
+	captured := make(chan string)
+	go func() {
+		content, _ := io.ReadAll(reader)
+		captured <- string(content)
+	}()
+	run()
+	writer.Close()
+	return <-captured
+}
+
+func TestOutputResultsJSONSinks(t *testing.T) {
+	words := map[string]int{"password": 1, "secret": 2}
+	want := "{\n  \"password\": 1,\n  \"secret\": 2\n}\n"
+	t.Run("stdout only", func(t *testing.T) {
+		config := &skweezConf{jsonOutput: true}
+		got := captureStdout(t, func() {
+			if err := outputResults(config, words); err != nil {
+				t.Error(err)
+			}
+		})
+		if got != want {
+			t.Errorf("got %q, want %q", got, want)
+		}
+	})
+	t.Run("file only", func(t *testing.T) {
+		config := &skweezConf{jsonOutput: true, output: filepath.Join(t.TempDir(), "words.json")}
+		stdout := captureStdout(t, func() {
+			if err := outputResults(config, words); err != nil {
+				t.Error(err)
+			}
+		})
+		if stdout != "" {
+			t.Errorf("got %q on stdout, want nothing", stdout)
+		}
+		if got := readFile(t, config.output); got != want {
+			t.Errorf("got %q, want %q", got, want)
+		}
+	})
+	t.Run("file and stdout", func(t *testing.T) {
+		config := &skweezConf{jsonOutput: true, output: filepath.Join(t.TempDir(), "words.json"), stdout: true}
+		stdout := captureStdout(t, func() {
+			if err := outputResults(config, words); err != nil {
+				t.Error(err)
+			}
+		})
+		if got := readFile(t, config.output); got != want || stdout != want {
+			t.Errorf("got %q in the file and %q on stdout, want %q in both", got, stdout, want)
+		}
+	})
+}
+
+func TestOutputResultsMinCount(t *testing.T) {
+	words := map[string]int{"password": 5, "secret": 2, "typo": 1, "noise": 1}
+	tests := []struct {
+		name   string
+		config skweezConf
+		want   string
+	}{
+		{"plain", skweezConf{sortMode: "freq", withCounts: true}, "password 5\nsecret 2\n"},
+		{"json", skweezConf{jsonOutput: true, jsonCompact: true}, "{\"password\":5,\"secret\":2}\n"},
+		{"csv", skweezConf{csvOutput: true, sortMode: "freq"}, "word,count\npassword,5\nsecret,2\n"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			config := test.config
+			config.minCount = 2
+			config.output = filepath.Join(t.TempDir(), "words")
+			if err := outputResults(&config, words); err != nil {
+				t.Fatal(err)
+			}
+			if got := readFile(t, config.output); got != test.want {
+				t.Errorf("got %q, want %q", got, test.want)
+			}
+		})
+	}
+}
+
+func TestExtractDomain(t *testing.T) {
+	tests := []struct {
+		uri  string
+		want string
+	}{
+		{"example.com", "example.com"},
+		{"https://example.com/path", "example.com"},
+		{"http://user:pw@example.com:8443/path", "example.com"},
+		{"http://[::1]:8080/", "::1"},
+		{"http://[2001:db8::1]/path", "2001:db8::1"},
+	}
+	for _, test := range tests {
+		if got := extractDomain(test.uri); got != test.want {
+			t.Errorf("extractDomain(%q) = %q, want %q", test.uri, got, test.want)
+		}
+	}
+}
+
+func TestScopeDomains(t *testing.T) {
+	tests := []struct {
+		uri  string
+		want []string
+	}{
+		{"https://example.com/", []string{"example.com"}},
+		{"http://user:pw@example.com/", []string{"example.com"}},
+		{"http://example.com:8443/", []string{"example.com", "example.com:8443"}},
+		// colly matches the host including the brackets of IPv6 addresses
+		{"http://[::1]/", []string{"::1", "[::1]"}},
+		{"http://[::1]:8080/", []string{"::1", "[::1]:8080"}},
+	}
+	for _, test := range tests {
+		if got := scopeDomains(test.uri); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("scopeDomains(%q) = %v, want %v", test.uri, got, test.want)
+		}
+	}
+}
+
+func TestExtractDomainPunycode(t *testing.T) {
+	tests := []struct {
+		uri  string
+		want string
+	}{
+		{"müller.de", "xn--mller-kva.de"},
+		{"https://müller.de/impressum", "xn--mller-kva.de"},
+		{"https://xn--mller-kva.de/", "xn--mller-kva.de"},
+	}
+	for _, test := range tests {
+		if got := extractDomain(test.uri); got != test.want {
+			t.Errorf("extractDomain(%q) = %q, want %q", test.uri, got, test.want)
+		}
+	}
+	if got, want := scopeDomains("http://müller.de:8080/"), []string{"xn--mller-kva.de", "xn--mller-kva.de:8080"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("scopeDomains = %v, want %v", got, want)
+	}
+}
+
+func TestCheckUnlimited(t *testing.T) {
+	tests := []struct {
+		name      string
+		unlimited bool
+		hasFiles  bool
+		maxPages  int
+		confirmed bool
+		wantErr   bool
+	}{
+		{"limited depth", false, false, 0, false, false},
+		{"unlimited without limit", true, false, 0, false, true},
+		{"unlimited with max pages", true, false, 100, false, false},
+		{"unlimited confirmed", true, false, 0, true, false},
+		{"unlimited on files", true, true, 0, false, false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := checkUnlimited(test.unlimited, test.hasFiles, test.maxPages, test.confirmed)
+			if (err != nil) != test.wantErr {
+				t.Errorf("got error %v, want error %v", err, test.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,226 @@
+/*
+Copyright © 2021 Michael Eder @edermi / twitter.com/michael_eder_
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+
+// Package skweez crawls websites and extracts the words found on them.
+package skweez
+
+import (
+	"regexp"
+	"time"
+
+	"golang.org/x/exp/slog"
+)
+
+// Config controls what skweez crawls and which words it keeps
+type Config struct {
+	// Targets are the URLs the crawl starts from
+	Targets []string
+	// Depth to spider. 0 = unlimited, 1 = only the targets, 2... = specific depth
+	Depth int
+	// TargetDepths overrides Depth for single targets, targets only given here are crawled as well
+	TargetDepths map[string]int
+	// Scope lists the domains allowed to be crawled, empty means unlimited
+	Scope []string
+	// URLFilters restrict crawling to URLs matching any of the regexps
+	URLFilters []*regexp.Regexp
+	// ExternalDepth allows visiting pages outside of Scope and URLFilters up to this many links away from in-scope pages
+	ExternalDepth int
+	// UserAgent replaces colly's default user-agent when set
+	UserAgent string
+	// Headers are added to every request, in the format key:value
+	Headers []string
+	// Delay between requests to the same domain
+	Delay time.Duration
+	// RandomDelay adds a random delay up to the given duration to Delay
+	RandomDelay time.Duration
+	// Parallelism is the number of concurrent requests per domain
+	Parallelism int
+	// DomainLimits override Delay and Parallelism for the domains matching their glob, the first match applies
+	DomainLimits []DomainLimit
+	// IgnoreRobots disables fetching and honoring robots.txt
+	IgnoreRobots bool
+	// UseSitemap seeds the crawl with the URLs in /sitemap.xml of each target
+	UseSitemap bool
+	// RequestTimeout for a single request, 0 = colly's default
+	RequestTimeout time.Duration
+	// Proxies are used round robin for all requests
+	Proxies []string
+	// MaxPages stops the crawl after the given number of pages, 0 = no limit
+	MaxPages int
+	// BasicAuth holds user:password credentials, only sent to the hosts of Targets and Scope
+	BasicAuth string
+	// CookieFile is a Netscape format cookie file (cookies.txt) whose cookies are sent
+	CookieFile string
+	// Retries is how often failed requests are retried with exponential backoff
+	Retries int
+	// ExcludeURLFilters prevent visiting URLs matching any of the regexps
+	ExcludeURLFilters []*regexp.Regexp
+	// IgnoreQueryParams are removed from links before visiting them
+	IgnoreQueryParams []string
+	// MaxSamePath limits the visits of a path with different query strings, 0 = no limit
+	MaxSamePath int
+	// NoQueryLinks skips links with a query string, the targets are visited regardless
+	NoQueryLinks bool
+	// NormalizeURLs removes trailing slashes from the paths of links
+	NormalizeURLs bool
+	// AllowRevisit visits URLs again every time they are linked
+	AllowRevisit bool
+	// MaxBodySize truncates response bodies larger than the given number of bytes, 0 = 10MB
+	MaxBodySize int
+	// ContentTypes are the media types of responses to extract words from, empty means DefaultContentTypes
+	ContentTypes []string
+	// Insecure disables the verification of TLS certificates
+	Insecure bool
+	// ClientCert and ClientKey are the PEM files of a client certificate for mutual TLS
+	ClientCert string
+	ClientKey  string
+	// CACert is a PEM file with the CA certificates to trust instead of the system ones
+	CACert string
+	// AcceptLanguage is sent as Accept-Language header if set
+	AcceptLanguage string
+	// StateFile regularly saves the visited URLs and words, an existing state file resumes the crawl
+	StateFile string
+	// RequestsPerSecond caps the requests per second across all domains, 0 = no limit
+	RequestsPerSecond float64
+	// CacheDir caches GET responses on disk and reuses them, they never expire
+	CacheDir string
+
+	// MinLen is the minimum word length in characters (inclusive)
+	MinLen int
+	// MaxLen is the maximum word length in characters (inclusive), 0 uses the MinLen and MaxLen of DefaultConfig
+	MaxLen int
+	// MinUniqueChars drops words with fewer distinct characters, 0 disables the check
+	MinUniqueChars int
+	// MustContainDigit drops words without any digit
+	MustContainDigit bool
+	// NoNumeric drops words made entirely of digits
+	NoNumeric bool
+	// NumbersOnly drops words that are not made entirely of digits
+	NumbersOnly bool
+	// CollectNumbers collects words made entirely of digits apart from the words, regardless of NoNumeric, see Crawler.Numbers
+	CollectNumbers bool
+	// WordPrefix and WordSuffix only keep words starting or ending with them, empty means any
+	WordPrefix string
+	WordSuffix string
+	// IgnoreAffixCase matches WordPrefix and WordSuffix case-insensitively
+	IgnoreAffixCase bool
+	// NoFilter keeps all strings, ignoring WordRegex, MinLen and MaxLen
+	NoFilter bool
+	// WordRegex decides what looks like a word, defaults to ValidWordRegex
+	WordRegex *regexp.Regexp
+	// OnlyASCII filters out words containing non-ASCII characters
+	OnlyASCII bool
+	// IncludeAttrs are HTML attributes whose values are extracted, too
+	IncludeAttrs []string
+	// IncludeFormFields extracts the name, id and placeholder of input, select, textarea and button elements
+	IncludeFormFields bool
+	// NoMeta disables extraction from description, keywords and og:* meta tags
+	NoMeta bool
+	// Stopwords are lowercased words that are filtered out
+	Stopwords map[string]bool
+	// Case normalizes words: preserve (default), lower or upper
+	Case string
+	// Normalize converts words to the Unicode normalization form NFC, so precomposed and combining characters are counted as the same word
+	Normalize bool
+	// SplitIdentifiers additionally counts the parts of identifiers like getUserName
+	SplitIdentifiers bool
+
+	// OnNewWords is called after every page with the words seen for the first time.
+	// Calls are never concurrent.
+	OnNewWords func(words []string)
+	// OnPage is called with the URL of every page after it was scraped.
+	// Calls are never concurrent.
+	OnPage func(url string)
+	// OnStatus is called with the status code and content type of every response, including failed requests.
+	// The status code is 0 if no response was received. Calls are never concurrent.
+	OnStatus func(url string, statusCode int, contentType string)
+	// MaxWords caps the number of unique words, 0 = no limit. Existing words are still counted
+	MaxWords int
+	// StopAtMaxWords stops the crawl once MaxWords is reached
+	StopAtMaxWords bool
+	// Language drops text blocks detected as another language, an ISO 639-1 or 639-3 code
+	Language string
+	// LanguageConfidence is the minimum detection confidence to drop a text block
+	LanguageConfidence float64
+	// SplitChars are additional separators inside of words, for example punctuation. Empty disables splitting
+	SplitChars string
+	// KeepOriginal keeps words split by SplitChars as a whole, too
+	KeepOriginal bool
+	// ExtractEmails collects email addresses found in text and mailto: links, see Crawler.Emails
+	ExtractEmails bool
+	// IncludeScripts extracts string literals and identifiers from inline and linked scripts instead of skipping them
+	IncludeScripts bool
+	// IncludeComments extracts words from HTML comments
+	IncludeComments bool
+	// IncludePDF extracts the text of PDF documents
+	IncludePDF bool
+	// DryRun follows links as usual but skips the extraction of words
+	DryRun bool
+	// MergeWords are added to the results before crawling, for example from existing word lists
+	MergeWords map[string]int
+	// SplitByDomain additionally counts the words per domain, see Crawler.DomainWords
+	SplitByDomain bool
+	// TrimChars are trimmed from the start and end of words, empty means DefaultTrimChars
+	TrimChars string
+	// NoTrim disables trimming TrimChars from words
+	NoTrim bool
+	// SkipDuplicates skips the extraction of words from responses with the same body as a previous one
+	SkipDuplicates bool
+	// ExcludeSelectors are CSS selectors of elements to remove before extracting words, like navigation or footers
+	ExcludeSelectors []string
+	// Readability only extracts words from the main content of a page as detected by readability
+	Readability bool
+	// ExcludeHidden removes elements hidden by the hidden attribute or inline styles before extracting words
+	ExcludeHidden bool
+	// VisibleOnly removes the head and elements that are never rendered, like scripts and templates, before extracting words
+	VisibleOnly bool
+	// Render loads pages again in headless Chrome to extract words and links from the DOM after running JavaScript.
+	// It requires a build with the render tag
+	Render bool
+
+	// Logger receives progress and debug output depending on its level, nil discards it
+	Logger *slog.Logger
+}
+
+// DomainLimit is the delay and parallelism of requests to the domains matching DomainGlob, like *.example.com
+type DomainLimit struct {
+	DomainGlob  string
+	Delay       time.Duration
+	Parallelism int
+}
+
+// ValidWordRegex matches strings that start and end with an alphanumeric ASCII character
+var ValidWordRegex = regexp.MustCompile(`^[a-zA-Z0-9]+.*[a-zA-Z0-9]$`)
+
+// ValidUnicodeWordRegex matches strings that start and end with a unicode letter or digit
+var ValidUnicodeWordRegex = regexp.MustCompile(`^[\p{L}\p{N}]+.*[\p{L}\p{N}]$`)
+
+// DefaultContentTypes are the media types of responses processed as HTML by default
+var DefaultContentTypes = []string{"text/html", "application/xhtml+xml"}
+
+// DefaultConfig returns a Config with the same defaults as the skweez command line tool
+func DefaultConfig() Config {
+	return Config{
+		Depth:       2,
+		Parallelism: 4,
+		MinLen:      3,
+		MaxLen:      24,
+		WordRegex:   ValidWordRegex,
+		TrimChars:   DefaultTrimChars,
+		Case:        "preserve",
+	}
+}
@@ -0,0 +1,89 @@
+/*
+Copyright © 2021 Michael Eder @edermi / twitter.com/michael_eder_
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package skweez
+
+import (
+	"bufio"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gocolly/colly"
+	"golang.org/x/exp/slog"
+)
+
+// loadCookieFile installs the cookies of a Netscape cookie file (cookies.txt) into the collector.
+// Malformed and expired cookies are skipped with a warning.
+func loadCookieFile(collector *colly.Collector, path string, logger *slog.Logger) error {
+	filedescriptor, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("could not open cookie file %s: %w", path, err)
+	}
+	defer filedescriptor.Close()
+	scanner := bufio.NewScanner(filedescriptor)
+	lineNumber := 0
+	for scanner.Scan() {
+		lineNumber++
+		line := strings.TrimSpace(scanner.Text())
+		httpOnly := strings.HasPrefix(line, "#HttpOnly_")
+		line = strings.TrimPrefix(line, "#HttpOnly_")
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		// domain, include subdomains, path, secure, expiry, name, value
+		fields := strings.Split(line, "\t")
+		if len(fields) != 7 {
+			logger.Warn("Skipping malformed line of cookie file", "file", path, "line", lineNumber)
+			continue
+		}
+		expiry, err := strconv.ParseInt(fields[4], 10, 64)
+		if err != nil {
+			logger.Warn("Skipping line of cookie file with invalid expiry", "file", path, "line", lineNumber, "expiry", fields[4])
+			continue
+		}
+		// 0 marks session cookies
+		if expiry != 0 && time.Unix(expiry, 0).Before(time.Now()) {
+			logger.Warn("Skipping expired cookie", "cookie", fields[5], "domain", fields[0])
+			continue
+		}
+		host := strings.TrimPrefix(fields[0], ".")
+		secure := strings.EqualFold(fields[3], "TRUE")
+		cookie := &http.Cookie{
+			Name:     fields[5],
+			Value:    fields[6],
+			Path:     fields[2],
+			Secure:   secure,
+			HttpOnly: httpOnly,
+		}
+		// a domain attribute makes the cookie valid for subdomains, too
+		if strings.EqualFold(fields[1], "TRUE") {
+			cookie.Domain = host
+		}
+		scheme := "http"
+		if secure {
+			scheme = "https"
+		}
+		if err := collector.SetCookies(scheme+"://"+host+fields[2], []*http.Cookie{cookie}); err != nil {
+			logger.Warn("Skipping cookie", "cookie", fields[5], "domain", fields[0], "err", err)
+		}
+	}
+	return scanner.Err()
+}
@@ -0,0 +1,894 @@
+/*
+Copyright © 2021 Michael Eder @edermi / twitter.com/michael_eder_
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package skweez
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gocolly/colly"
+	"github.com/gocolly/colly/proxy"
+	"golang.org/x/exp/maps"
+	"golang.org/x/exp/slices"
+	"golang.org/x/exp/slog"
+	"golang.org/x/time/rate"
+)
+
+// Crawler spiders the targets of its Config and collects words
+type Crawler struct {
+	config      Config
+	emails      []string
+	numbers     []string
+	domainWords map[string]map[string]int
+	// mu guards cache and stats of the current Run, which Progress reads concurrently
+	mu    sync.Mutex
+	cache *wordCache
+	stats *crawlStats
+}
+
+// Progress is a snapshot of a running crawl
+type Progress struct {
+	// Pages is the number of pages scraped so far
+	Pages int
+	// Pending is the number of requests in flight or waiting to be sent
+	Pending int
+	// Words is the number of unique words found so far
+	Words int
+	// StatusCodes counts the responses per HTTP status code, 0 counts requests failing without a response
+	StatusCodes map[int]int
+}
+
+// crawlStats counts pages and requests, callbacks run concurrently
+type crawlStats struct {
+	pages       int64
+	pending     int64
+	mu          sync.Mutex
+	statusCodes map[int]int
+}
+
+// statusCodesSnapshot copies the counts of the status codes
+func (stats *crawlStats) statusCodesSnapshot() map[int]int {
+	stats.mu.Lock()
+	defer stats.mu.Unlock()
+	return maps.Clone(stats.statusCodes)
+}
+
+// addStatus counts a response with the given status code
+func (stats *crawlStats) addStatus(code int) {
+	stats.mu.Lock()
+	defer stats.mu.Unlock()
+	if stats.statusCodes == nil {
+		stats.statusCodes = make(map[int]int)
+	}
+	stats.statusCodes[code]++
+}
+
+// NewCrawler creates a Crawler from a Config
+func NewCrawler(config Config) *Crawler {
+	if config.WordRegex == nil {
+		config.WordRegex = ValidWordRegex
+	}
+	if config.TrimChars == "" {
+		config.TrimChars = DefaultTrimChars
+	}
+	// no word is ever shorter than a MaxLen of 0, fall back to the default lengths
+	if config.MaxLen == 0 {
+		defaults := DefaultConfig()
+		config.MinLen, config.MaxLen = defaults.MinLen, defaults.MaxLen
+	}
+	if config.Logger == nil {
+		config.Logger = slog.New(slog.NewTextHandler(io.Discard))
+	}
+	return &Crawler{config: config}
+}
+
+// Run crawls the targets and returns the words found along with their counts.
+// Once ctx is done, no new pages are visited and the words collected so far are returned.
+func (crawler *Crawler) Run(ctx context.Context) (map[string]int, error) {
+	cache := newWordCache()
+	for word, count := range crawler.config.MergeWords {
+		cache.words[word] += count
+	}
+	cache.trackFresh = crawler.config.OnNewWords != nil
+	cache.maxWords = crawler.config.MaxWords
+	cache.onFull = func() {
+		crawler.config.Logger.Warn("Reached the maximum of unique words, the word list is truncated", "max_words", crawler.config.MaxWords)
+	}
+	stats := &crawlStats{}
+	crawler.mu.Lock()
+	crawler.cache, crawler.stats = cache, stats
+	crawler.mu.Unlock()
+	var state *crawlState
+	if crawler.config.StateFile != "" {
+		loaded, words, err := loadState(crawler.config.StateFile)
+		if err != nil {
+			return nil, err
+		}
+		state = loaded
+		for word, count := range words {
+			cache.words[word] += count
+		}
+	}
+	c, err := initColly(&crawler.config)
+	if err != nil {
+		return nil, err
+	}
+	if crawler.config.IncludePDF {
+		if err := pdfSupport(); err != nil {
+			return nil, err
+		}
+	}
+	var render *renderer
+	if crawler.config.Render {
+		if render, err = newRenderer(&crawler.config); err != nil {
+			return nil, err
+		}
+		defer render.Close()
+	}
+	registerCallbacks(ctx, c, &crawler.config, cache, stats, state, render)
+
+	for _, toVisit := range crawler.targets() {
+		depth, ok := crawler.config.TargetDepths[toVisit]
+		if !ok {
+			depth = crawler.config.Depth
+		}
+		visitWithDepth(c, toVisit, depth)
+		if crawler.config.UseSitemap {
+			visitSitemap(c, sitemapUri(toVisit))
+		}
+	}
+	var stopSaving func()
+	if state != nil {
+		for pending, depth := range state.Pending() {
+			visitWithDepth(c, pending, depth)
+		}
+		stopSaving = saveStatePeriodically(state, cache, crawler.config.Logger)
+	}
+	c.Wait()
+	if state != nil {
+		stopSaving()
+		if err := state.Save(cache.snapshot()); err != nil {
+			return nil, err
+		}
+	}
+	crawler.emails = cache.sortedEmails()
+	crawler.numbers = cache.sortedNumbers()
+	crawler.domainWords = cache.domainWords()
+	return cache.words, nil
+}
+
+// saveStatePeriodically saves the state every stateInterval until the returned stop function is called
+func saveStatePeriodically(state *crawlState, cache *wordCache, logger *slog.Logger) (stop func()) {
+	done := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(stateInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				if err := state.Save(cache.snapshot()); err != nil {
+					logger.Error("Could not save state", "err", err)
+				}
+			}
+		}
+	}()
+	return func() { close(done) }
+}
+
+// targets returns the targets along with the ones only given in TargetDepths
+func (crawler *Crawler) targets() []string {
+	targets := slices.Clone(crawler.config.Targets)
+	var extraTargets []string
+	for target := range crawler.config.TargetDepths {
+		if !slices.Contains(targets, target) {
+			extraTargets = append(extraTargets, target)
+		}
+	}
+	slices.Sort(extraTargets)
+	return append(targets, extraTargets...)
+}
+
+// Emails returns the sorted email addresses found by the last Run, if Config.ExtractEmails is set
+func (crawler *Crawler) Emails() []string {
+	return crawler.emails
+}
+
+// Numbers returns the sorted words made entirely of digits found by the last Run, if Config.CollectNumbers is set
+func (crawler *Crawler) Numbers() []string {
+	return crawler.numbers
+}
+
+// Progress returns the progress of the current or last Run, it is safe to call while Run is in progress
+func (crawler *Crawler) Progress() Progress {
+	crawler.mu.Lock()
+	defer crawler.mu.Unlock()
+	if crawler.stats == nil {
+		return Progress{}
+	}
+	return Progress{
+		Pages:   int(atomic.LoadInt64(&crawler.stats.pages)),
+		Pending: int(atomic.LoadInt64(&crawler.stats.pending)),
+		Words:   crawler.cache.Len(),
+		// the crawl may still be running
+		StatusCodes: crawler.stats.statusCodesSnapshot(),
+	}
+}
+
+// DomainWords returns the words found by the last Run per host including the port, if Config.SplitByDomain is set
+func (crawler *Crawler) DomainWords() map[string]map[string]int {
+	return crawler.domainWords
+}
+
+// wordCache counts word occurrences and is safe for concurrent use
+type wordCache struct {
+	mu    sync.Mutex
+	words map[string]int
+	// fresh holds words seen for the first time since the last flushFresh, if tracked
+	trackFresh bool
+	fresh      []string
+	// maxWords caps the number of unique words, onFull is called once when reaching it
+	maxWords int
+	full     bool
+	onFull   func()
+	// emails is the set of email addresses, kept apart from the words
+	emails map[string]bool
+	// numbers is the set of words made entirely of digits, kept apart from the words
+	numbers map[string]bool
+	// domains holds a cache per domain, their words are also added to this cache as their parent
+	domains map[string]*wordCache
+	parent  *wordCache
+}
+
+func newWordCache() *wordCache {
+	return &wordCache{words: make(map[string]int), emails: make(map[string]bool), numbers: make(map[string]bool)}
+}
+
+// Add increments the count of a word and reports whether it was counted
+func (wc *wordCache) Add(word string) bool {
+	if wc.parent != nil && !wc.parent.Add(word) {
+		return false
+	}
+	wc.mu.Lock()
+	defer wc.mu.Unlock()
+	if wc.maxWords > 0 && wc.words[word] == 0 && len(wc.words) >= wc.maxWords {
+		if !wc.full {
+			wc.full = true
+			if wc.onFull != nil {
+				wc.onFull()
+			}
+		}
+		return false
+	}
+	if wc.trackFresh && wc.words[word] == 0 {
+		wc.fresh = append(wc.fresh, word)
+	}
+	wc.words[word] += 1
+	return true
+}
+
+// domain returns the cache of a domain, creating it on first use
+func (wc *wordCache) domain(domain string) *wordCache {
+	wc.mu.Lock()
+	defer wc.mu.Unlock()
+	if wc.domains == nil {
+		wc.domains = make(map[string]*wordCache)
+	}
+	domainCache, ok := wc.domains[domain]
+	if !ok {
+		domainCache = newWordCache()
+		domainCache.parent = wc
+		wc.domains[domain] = domainCache
+	}
+	return domainCache
+}
+
+// domainWords returns the words per domain
+func (wc *wordCache) domainWords() map[string]map[string]int {
+	wc.mu.Lock()
+	defer wc.mu.Unlock()
+	words := make(map[string]map[string]int, len(wc.domains))
+	for domain, domainCache := range wc.domains {
+		words[domain] = domainCache.words
+	}
+	return words
+}
+
+// AddEmail adds an email address, lowercased, to the set of email addresses
+func (wc *wordCache) AddEmail(email string) {
+	if wc.parent != nil {
+		wc.parent.AddEmail(email)
+		return
+	}
+	wc.mu.Lock()
+	defer wc.mu.Unlock()
+	wc.emails[strings.ToLower(email)] = true
+}
+
+// sortedEmails returns the collected email addresses in alphabetical order
+func (wc *wordCache) sortedEmails() []string {
+	wc.mu.Lock()
+	defer wc.mu.Unlock()
+	emails := maps.Keys(wc.emails)
+	slices.Sort(emails)
+	return emails
+}
+
+// AddNumber adds a word made entirely of digits to the set of numbers
+func (wc *wordCache) AddNumber(number string) {
+	if wc.parent != nil {
+		wc.parent.AddNumber(number)
+		return
+	}
+	wc.mu.Lock()
+	defer wc.mu.Unlock()
+	wc.numbers[number] = true
+}
+
+// sortedNumbers returns the collected numbers in alphabetical order
+func (wc *wordCache) sortedNumbers() []string {
+	wc.mu.Lock()
+	defer wc.mu.Unlock()
+	numbers := maps.Keys(wc.numbers)
+	slices.Sort(numbers)
+	return numbers
+}
+
+// snapshot returns a copy of the words and their counts
+func (wc *wordCache) snapshot() map[string]int {
+	wc.mu.Lock()
+	defer wc.mu.Unlock()
+	words := make(map[string]int, len(wc.words))
+	for word, count := range wc.words {
+		words[word] = count
+	}
+	return words
+}
+
+// Len returns the number of unique words
+func (wc *wordCache) Len() int {
+	wc.mu.Lock()
+	defer wc.mu.Unlock()
+	return len(wc.words)
+}
+
+// Full reports whether the maximum number of unique words was reached
+func (wc *wordCache) Full() bool {
+	wc.mu.Lock()
+	defer wc.mu.Unlock()
+	return wc.full
+}
+
+// flushFresh passes the words seen for the first time since the last call to fn
+func (wc *wordCache) flushFresh(fn func(words []string)) {
+	wc.mu.Lock()
+	defer wc.mu.Unlock()
+	if len(wc.fresh) > 0 {
+		fn(wc.fresh)
+	}
+	wc.fresh = nil
+}
+
+func initColly(config *Config) (*colly.Collector, error) {
+	scope, filters := config.Scope, config.URLFilters
+	if config.ExternalDepth > 0 {
+		// the scope is checked in OnRequest instead, to let external links through
+		scope, filters = nil, nil
+	}
+	c := colly.NewCollector(
+		colly.MaxDepth(maxDepth(config)),
+		colly.AllowedDomains(scope...),
+		colly.URLFilters(filters...),
+		colly.Async(true),
+	)
+	if config.UserAgent != "" {
+		c.UserAgent = config.UserAgent
+	}
+	c.AllowURLRevisit = config.AllowRevisit
+	c.IgnoreRobotsTxt = config.IgnoreRobots
+	c.CacheDir = config.CacheDir
+	if config.MaxBodySize > 0 {
+		c.MaxBodySize = config.MaxBodySize
+	}
+	if config.RequestTimeout > 0 {
+		c.SetRequestTimeout(config.RequestTimeout)
+	}
+	// the transport has to be set before the proxies, which are configured on it
+	transport, err := newTransport(config)
+	if err != nil {
+		return nil, err
+	}
+	if transport != nil {
+		c.WithTransport(transport)
+	}
+	if len(config.Proxies) > 0 {
+		proxyFunc, err := proxy.RoundRobinProxySwitcher(config.Proxies...)
+		if err != nil {
+			return nil, err
+		}
+		c.SetProxyFunc(proxyFunc)
+	}
+	if config.CookieFile != "" {
+		if err := loadCookieFile(c, config.CookieFile, config.Logger); err != nil {
+			return nil, err
+		}
+	}
+	// colly applies the first matching rule, so the catch-all rule comes last
+	var rules []*colly.LimitRule
+	for _, limit := range config.DomainLimits {
+		rules = append(rules, &colly.LimitRule{
+			DomainGlob:  limit.DomainGlob,
+			Parallelism: limit.Parallelism,
+			Delay:       limit.Delay,
+			RandomDelay: config.RandomDelay,
+		})
+	}
+	rules = append(rules, &colly.LimitRule{
+		DomainGlob:  "*",
+		Parallelism: config.Parallelism,
+		Delay:       config.Delay,
+		RandomDelay: config.RandomDelay,
+	})
+	err = c.Limits(rules)
+	return c, err
+}
+
+// maxDepth returns the largest depth of Depth and TargetDepths, the depth per target is checked in OnRequest
+func maxDepth(config *Config) int {
+	depth := config.Depth
+	for _, targetDepth := range config.TargetDepths {
+		if depth == 0 || targetDepth == 0 {
+			return 0
+		}
+		if targetDepth > depth {
+			depth = targetDepth
+		}
+	}
+	return depth
+}
+
+func registerCallbacks(ctx context.Context, collector *colly.Collector, config *Config, cache *wordCache, stats *crawlStats, state *crawlState, render *renderer) {
+	logger := config.Logger
+	// pages counts the scraped pages, callbacks run concurrently
+	var pages int64
+	samePath := newPathCounter()
+	bodies := newBodyHashes()
+	// onPageMu serializes the calls of config.OnPage
+	var onPageMu sync.Mutex
+	// onStatusMu serializes the calls of config.OnStatus
+	var onStatusMu sync.Mutex
+	reportStatus := func(r *colly.Response) {
+		if config.OnStatus == nil {
+			return
+		}
+		contentType := ""
+		if r.Headers != nil {
+			contentType = r.Headers.Get("Content-Type")
+		}
+		onStatusMu.Lock()
+		config.OnStatus(r.Request.URL.String(), r.StatusCode, contentType)
+		onStatusMu.Unlock()
+	}
+	// limiter caps the requests per second across all domains
+	var limiter *rate.Limiter
+	if config.RequestsPerSecond > 0 {
+		limiter = rate.NewLimiter(rate.Limit(config.RequestsPerSecond), 1)
+	}
+
+	// visitLink follows a link found on the page of a request
+	visitLink := func(r *colly.Request, href string) {
+		link := normalizeURL(stripQueryParams(r.AbsoluteURL(href), config.IgnoreQueryParams), config.NormalizeURLs)
+		if config.NoQueryLinks && hasQuery(link) {
+			return
+		}
+		if config.ExternalDepth > 0 && !allowExternal(config, r, link) {
+			return
+		}
+		r.Visit(link)
+	}
+	collector.OnHTML("a[href]", func(e *colly.HTMLElement) {
+		visitLink(e.Request, e.Attr("href"))
+	})
+
+	if config.UseSitemap {
+		// sitemap indexes point to further sitemaps, regular sitemaps to pages
+		collector.OnXML("//sitemapindex/sitemap/loc", func(e *colly.XMLElement) {
+			visitSitemap(collector, strings.TrimSpace(e.Text))
+		})
+		collector.OnXML("//urlset/url/loc", func(e *colly.XMLElement) {
+			visitWithDepth(collector, normalizeURL(strings.TrimSpace(e.Text), config.NormalizeURLs), config.Depth)
+		})
+	}
+
+	if config.IncludeScripts && !config.DryRun {
+		collector.OnHTML("script[src]", func(e *colly.HTMLElement) {
+			visitScript(collector, e.Request.AbsoluteURL(e.Attr("src")))
+		})
+	}
+
+	collector.OnRequest(func(r *colly.Request) {
+		// the depth limit is part of the context shared by all requests originating from a target
+		depth, err := strconv.Atoi(r.Ctx.Get("depth"))
+		if err != nil {
+			depth = config.Depth
+		}
+		if depth > 0 && r.Depth > depth {
+			r.Abort()
+			return
+		}
+		// remainingDepth is the depth limit of this URL when visited again as pending URL of the state
+		remainingDepth := 0
+		if depth > 0 {
+			remainingDepth = depth - r.Depth + 1
+		}
+		isPage := r.Ctx.Get("sitemap") == "" && r.Ctx.Get("script") == ""
+		if config.ExternalDepth > 0 && !inScope(config, r.URL) && r.Ctx.GetAny(externalKey(r.URL.String())) == nil {
+			r.Abort()
+			return
+		}
+		if state != nil && isPage && state.Visited(r.URL.String()) {
+			r.Abort()
+			return
+		}
+		// stop crawling once the context is done, requests in flight still finish
+		if ctx.Err() != nil {
+			// the URL is visited when resuming the crawl from the state
+			if state != nil && isPage {
+				state.Requested(r.URL.String(), remainingDepth)
+			}
+			r.Abort()
+			return
+		}
+		if config.MaxPages > 0 && atomic.LoadInt64(&pages) >= int64(config.MaxPages) {
+			r.Abort()
+			return
+		}
+		if config.StopAtMaxWords && cache.Full() {
+			r.Abort()
+			return
+		}
+		// retries were already counted on their first attempt
+		if config.MaxSamePath > 0 && r.Ctx.Get("retries") == "" && !samePath.Allow(r.URL, config.MaxSamePath) {
+			logger.Debug("Skipping, path visited too often", "url", r.URL.String())
+			r.Abort()
+			return
+		}
+		for _, filter := range config.ExcludeURLFilters {
+			if filter.MatchString(r.URL.String()) {
+				r.Abort()
+				return
+			}
+		}
+		if limiter != nil {
+			// Wait fails once ctx is done, the request is then treated like any other after the end of the crawl
+			if err := limiter.Wait(ctx); err != nil {
+				if state != nil && isPage {
+					state.Requested(r.URL.String(), remainingDepth)
+				}
+				r.Abort()
+				return
+			}
+		}
+		if config.AcceptLanguage != "" {
+			r.Headers.Set("Accept-Language", config.AcceptLanguage)
+		}
+		if len(config.Headers) > 0 {
+			for _, header := range config.Headers {
+				var headerSplit = strings.SplitN(header, ":", 2)
+				if len(headerSplit) > 1 {
+					// header needs to be trimmed otherwise colly wont send request
+					r.Headers.Set(strings.TrimSpace(headerSplit[0]), strings.TrimSpace(headerSplit[1]))
+				}
+			}
+		}
+		if config.BasicAuth != "" && credentialsAllowed(config, r.URL) {
+			r.Headers.Set("Authorization", "Basic "+base64.StdEncoding.EncodeToString([]byte(config.BasicAuth)))
+		}
+		logger.Debug("Visiting", "url", r.URL.String())
+		if state != nil && isPage {
+			state.Requested(r.URL.String(), remainingDepth)
+		}
+		atomic.AddInt64(&stats.pending, 1)
+	})
+
+	collector.OnError(func(r *colly.Response, err error) {
+		atomic.AddInt64(&stats.pending, -1)
+		stats.addStatus(r.StatusCode)
+		reportStatus(r)
+		logger.Debug("Something went wrong", "url", r.Request.URL.String(), "err", err)
+		retried := config.Retries > 0 && ctx.Err() == nil && retryable(r) && retry(ctx, r, config, logger)
+		if state != nil && !retried && r.Ctx.Get("sitemap") == "" && r.Ctx.Get("script") == "" {
+			state.Finished(r.Request.URL.String())
+		}
+	})
+
+	collector.OnResponse(func(r *colly.Response) {
+		stats.addStatus(r.StatusCode)
+		reportStatus(r)
+		logger.Debug("Visited", "url", r.Request.URL.String())
+	})
+
+	collector.OnScraped(func(r *colly.Response) {
+		atomic.AddInt64(&stats.pending, -1)
+		isSitemap := r.Ctx.Get("sitemap") != ""
+		isScript := r.Ctx.Get("script") != ""
+		if state != nil && !isSitemap && !isScript {
+			state.Finished(r.Request.URL.String())
+		}
+		if !isSitemap && !isScript {
+			// requests already in flight when the limit is reached are discarded
+			if scraped := atomic.AddInt64(&pages, 1); config.MaxPages > 0 && scraped > int64(config.MaxPages) {
+				return
+			}
+		}
+		// https://stackoverflow.com/questions/44441665/how-to-extract-only-text-from-html-in-golang
+		logger.Info("Finished", "url", r.Request.URL.String())
+
+		if !isSitemap && !isScript {
+			atomic.AddInt64(&stats.pages, 1)
+			if config.OnPage != nil {
+				onPageMu.Lock()
+				config.OnPage(r.Request.URL.String())
+				onPageMu.Unlock()
+			}
+		}
+		pageCache := cache
+		if config.SplitByDomain {
+			// the port is part of the key, different services on one host are kept apart
+			pageCache = cache.domain(r.Request.URL.Host)
+		}
+		switch {
+		case isSitemap || config.DryRun:
+			return
+		case config.SkipDuplicates && bodies.Seen(r.Body):
+			logger.Debug("Skipping, same content as a previous page", "url", r.Request.URL.String())
+		case isScript:
+			extractScript(string(r.Body), config, pageCache)
+		case isPDF(r):
+			if !config.IncludePDF {
+				break
+			}
+			// encrypted or broken documents are skipped
+			if err := extractPDF(r.Body, config, pageCache); err != nil {
+				logger.Debug("Skipping PDF", "url", r.Request.URL.String(), "err", err)
+			}
+		case isXML(r):
+			if err := extractXML(r.Body, config, pageCache); err != nil {
+				logger.Debug("Stopped parsing XML", "url", r.Request.URL.String(), "err", err)
+			}
+		case !allowedContentType(r, config):
+			logger.Debug("Skipping, Content-Type not allowed", "url", r.Request.URL.String(), "content_type", r.Headers.Get("Content-Type"))
+		default:
+			body := r.Body
+			if render != nil {
+				// the page is fetched again, within the same rate limit
+				if limiter != nil {
+					limiter.Wait(ctx)
+				}
+				rendered, err := render.Render(r.Request.URL.String(), collector.Cookies(r.Request.URL.String()))
+				if err != nil {
+					logger.Debug("Rendering failed, using the page as is", "url", r.Request.URL.String(), "err", err)
+				} else {
+					body = rendered
+					// links already found on the page as is are not visited again
+					for _, href := range renderedLinks(rendered) {
+						visitLink(r.Request, href)
+					}
+				}
+			}
+			extractWords(body, config, pageCache)
+		}
+		if config.OnNewWords != nil {
+			cache.flushFresh(config.OnNewWords)
+		}
+	})
+}
+
+// bodyHashes remembers the hashes of response bodies to detect duplicate pages
+type bodyHashes struct {
+	mu   sync.Mutex
+	seen map[[sha256.Size]byte]bool
+}
+
+func newBodyHashes() *bodyHashes {
+	return &bodyHashes{seen: make(map[[sha256.Size]byte]bool)}
+}
+
+// Seen reports whether the same body was seen before and remembers it
+func (bh *bodyHashes) Seen(body []byte) bool {
+	hash := sha256.Sum256(body)
+	bh.mu.Lock()
+	defer bh.mu.Unlock()
+	if bh.seen[hash] {
+		return true
+	}
+	bh.seen[hash] = true
+	return false
+}
+
+// retryable checks if a failed request might succeed when tried again
+func retryable(r *colly.Response) bool {
+	// status code 0 means the request failed without a response, e.g. a connection reset
+	return r.StatusCode == 0 || r.StatusCode == http.StatusTooManyRequests || r.StatusCode >= 500
+}
+
+// retry waits for Retry-After or an exponential backoff and requests the page again,
+// up to config.Retries times, and reports whether it did. The attempts are tracked in the request context.
+func retry(ctx context.Context, r *colly.Response, config *Config, logger *slog.Logger) bool {
+	attempt, _ := strconv.Atoi(r.Ctx.Get("retries"))
+	if attempt >= config.Retries {
+		logger.Debug("Giving up", "url", r.Request.URL.String(), "retries", attempt)
+		return false
+	}
+	wait := time.Second << attempt
+	if r.StatusCode == http.StatusTooManyRequests && r.Headers != nil {
+		if retryAfter := parseRetryAfter(r.Headers.Get("Retry-After")); retryAfter > 0 {
+			wait = retryAfter
+		}
+	}
+	if wait > maxRetryWait {
+		wait = maxRetryWait
+	}
+	logger.Debug("Retrying", "url", r.Request.URL.String(), "wait", wait)
+	// the timeout and interrupts end the wait
+	select {
+	case <-time.After(wait):
+	case <-ctx.Done():
+		return false
+	}
+	r.Ctx.Put("retries", strconv.Itoa(attempt+1))
+	return r.Request.Retry() == nil
+}
+
+// maxRetryWait caps the wait before a retry, servers may ask for hours in Retry-After
+const maxRetryWait = time.Minute
+
+// parseRetryAfter parses a Retry-After header given in seconds or as HTTP date
+func parseRetryAfter(value string) time.Duration {
+	if seconds, err := strconv.Atoi(value); err == nil {
+		return time.Duration(seconds) * time.Second
+	}
+	if date, err := http.ParseTime(value); err == nil {
+		return time.Until(date)
+	}
+	return 0
+}
+
+// credentialsAllowed checks if a URL belongs to the targets or scope, so credentials do not leak
+// to other sites, for example when following redirects or with unlimited scope
+func credentialsAllowed(config *Config, uri *url.URL) bool {
+	hosts := append([]string{}, config.Scope...)
+	for _, target := range config.Targets {
+		if parsed, err := url.Parse(target); err == nil {
+			hosts = append(hosts, parsed.Host)
+		}
+	}
+	for _, host := range hosts {
+		if host == uri.Host || host == uri.Hostname() {
+			return true
+		}
+	}
+	return false
+}
+
+// inScope checks a URL against Scope and URLFilters the same way colly does
+func inScope(config *Config, uri *url.URL) bool {
+	if len(config.Scope) > 0 && !slices.Contains(config.Scope, uri.Host) {
+		return false
+	}
+	if len(config.URLFilters) == 0 {
+		return true
+	}
+	for _, filter := range config.URLFilters {
+		if filter.MatchString(uri.String()) {
+			return true
+		}
+	}
+	return false
+}
+
+// allowExternal checks if a link may be followed with ExternalDepth. The number of hops out of scope is
+// stored per URL in the context shared by the requests of a target, in-scope pages are at zero hops.
+func allowExternal(config *Config, r *colly.Request, link string) bool {
+	uri, err := url.Parse(link)
+	if err != nil || inScope(config, uri) {
+		return true
+	}
+	hops, _ := r.Ctx.GetAny(externalKey(r.URL.String())).(int)
+	if hops >= config.ExternalDepth {
+		return false
+	}
+	r.Ctx.Put(externalKey(uri.String()), hops+1)
+	return true
+}
+
+// externalKey is the context key of the hops out of scope of a URL
+func externalKey(uri string) string {
+	return "external " + uri
+}
+
+// visitSitemap enqueues a sitemap, marking the request so its words are not extracted
+func visitSitemap(collector *colly.Collector, uri string) {
+	ctx := colly.NewContext()
+	ctx.Put("sitemap", "true")
+	collector.Request("GET", uri, nil, ctx, nil)
+}
+
+// mediaType returns the lowercase media type of the Content-Type of a response without parameters like charset
+func mediaType(r *colly.Response) string {
+	mediaType, _, err := mime.ParseMediaType(r.Headers.Get("Content-Type"))
+	if err != nil {
+		return ""
+	}
+	return mediaType
+}
+
+// isPDF checks the Content-Type of a response for a PDF document
+func isPDF(r *colly.Response) bool {
+	return mediaType(r) == "application/pdf"
+}
+
+// allowedContentType checks whether the words of a response should be extracted based on its Content-Type.
+// Responses without Content-Type are processed as HTML.
+func allowedContentType(r *colly.Response, config *Config) bool {
+	if r.Headers.Get("Content-Type") == "" {
+		return true
+	}
+	contentTypes := config.ContentTypes
+	if len(contentTypes) == 0 {
+		contentTypes = DefaultContentTypes
+	}
+	return slices.Contains(contentTypes, mediaType(r))
+}
+
+// visitWithDepth requests a target, links found on it and the pages below are followed up to the given depth
+func visitWithDepth(collector *colly.Collector, uri string, depth int) {
+	ctx := colly.NewContext()
+	ctx.Put("depth", strconv.Itoa(depth))
+	collector.Request("GET", uri, nil, ctx, nil)
+}
+
+// visitScript requests a linked script, which is not counted as a page
+func visitScript(collector *colly.Collector, uri string) {
+	if uri == "" {
+		return
+	}
+	ctx := colly.NewContext()
+	ctx.Put("script", "true")
+	collector.Request("GET", uri, nil, ctx, nil)
+}
+
+// sitemapUri returns the default sitemap location of a target
+func sitemapUri(target string) string {
+	parsed, err := url.Parse(target)
+	if err != nil {
+		return strings.TrimSuffix(target, "/") + "/sitemap.xml"
+	}
+	return parsed.Scheme + "://" + parsed.Host + "/sitemap.xml"
+}
@@ -0,0 +1,102 @@
+/*
+Copyright © 2021 Michael Eder @edermi / twitter.com/michael_eder_
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package skweez
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+// crawl runs a crawl of a single page served by a test server with the default config modified by configure
+func crawl(t *testing.T, page string, configure func(config *Config)) map[string]int {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		fmt.Fprint(w, page)
+	}))
+	defer server.Close()
+	config := DefaultConfig()
+	config.Targets = []string{server.URL}
+	config.Depth = 1
+	config.IgnoreRobots = true
+	if configure != nil {
+		configure(&config)
+	}
+	words, err := NewCrawler(config).Run(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return words
+}
+
+func TestCrawlMaxBodySize(t *testing.T) {
+	page := "<html><body><p>first " + strings.Repeat("filler ", 1000) + "last</p></body></html>"
+	t.Run("truncated", func(t *testing.T) {
+		words := crawl(t, page, func(config *Config) {
+			config.MaxBodySize = 1024
+		})
+		if words["first"] != 1 || words["last"] != 0 {
+			t.Errorf("got first %d and last %d times, want the body truncated after first", words["first"], words["last"])
+		}
+	})
+	t.Run("complete", func(t *testing.T) {
+		words := crawl(t, page, nil)
+		if words["first"] != 1 || words["last"] != 1 {
+			t.Errorf("got first %d and last %d times, want both once", words["first"], words["last"])
+		}
+	})
+}
+
+func TestCrawlMaxSamePathRetry(t *testing.T) {
+	var failed int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		switch r.URL.Query().Get("id") {
+		case "":
+			fmt.Fprint(w, `<html><body><a href="/item?id=1">first</a> <a href="/item?id=2">second</a></body></html>`)
+		case "1":
+			// the first attempt fails, the retry must not count as another visit of the path
+			if atomic.AddInt32(&failed, 1) == 1 {
+				w.WriteHeader(http.StatusServiceUnavailable)
+				return
+			}
+			fmt.Fprint(w, `<html><body><p>alpha</p></body></html>`)
+		default:
+			fmt.Fprint(w, `<html><body><p>bravo</p></body></html>`)
+		}
+	}))
+	defer server.Close()
+	config := DefaultConfig()
+	config.Targets = []string{server.URL}
+	config.Depth = 2
+	config.IgnoreRobots = true
+	config.Retries = 1
+	config.MaxSamePath = 2
+	words, err := NewCrawler(config).Run(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if words["alpha"] != 1 || words["bravo"] != 1 {
+		t.Errorf("got alpha %d and bravo %d times, want both once", words["alpha"], words["bravo"])
+	}
+}
@@ -0,0 +1,457 @@
+/*
+Copyright © 2021 Michael Eder @edermi / twitter.com/michael_eder_
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package skweez
+
+import (
+	"bytes"
+	"net/url"
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/abadojack/whatlanggo"
+	"github.com/go-shiori/go-readability"
+	"golang.org/x/exp/slices"
+	"golang.org/x/exp/utf8string"
+	"golang.org/x/net/html"
+	"golang.org/x/text/unicode/norm"
+)
+
+// DefaultTrimChars are trimmed from the start and end of words, which is all ASCII punctuation
+const DefaultTrimChars = "!\"#$%&'()*+,-./:;<=>?@[\\]^_`{|}~"
+
+// DefaultSplitChars are the separators used to split words on punctuation, including typographic apostrophes
+const DefaultSplitChars = DefaultTrimChars + "’"
+
+// Extracted holds everything ExtractAll found in a document
+type Extracted struct {
+	// Words are the words along with their counts
+	Words map[string]int
+	// Emails are the sorted email addresses if Config.ExtractEmails is set
+	Emails []string
+	// Numbers are the sorted words made entirely of digits if Config.CollectNumbers is set
+	Numbers []string
+}
+
+// ExtractWords returns the words of an HTML document along with their counts.
+// Unset fields of the config fall back to the values of DefaultConfig where a zero value would drop all words.
+func ExtractWords(body []byte, config Config) map[string]int {
+	return ExtractAll(body, config).Words
+}
+
+// ExtractAll returns the words of an HTML document along with the email addresses and numbers found in it
+func ExtractAll(body []byte, config Config) Extracted {
+	if config.WordRegex == nil {
+		config.WordRegex = ValidWordRegex
+	}
+	if config.TrimChars == "" {
+		config.TrimChars = DefaultTrimChars
+	}
+	// no word is ever shorter than a MaxLen of 0, fall back to the default lengths
+	if config.MaxLen == 0 {
+		defaults := DefaultConfig()
+		config.MinLen, config.MaxLen = defaults.MinLen, defaults.MaxLen
+	}
+	cache := newWordCache()
+	extractWords(body, &config, cache)
+	return Extracted{Words: cache.words, Emails: cache.sortedEmails(), Numbers: cache.sortedNumbers()}
+}
+
+// leftoverEntityRegex matches tokens that are HTML entities surviving unescaping, like double escaped &amp;amp;
+var leftoverEntityRegex = regexp.MustCompile(`^&(#[0-9]+|#[xX][0-9a-fA-F]+|[a-zA-Z]+[0-9]*);?$`)
+
+// emailRegex matches email addresses within a text
+var emailRegex = regexp.MustCompile(`[a-zA-Z0-9._%+-]+@[a-zA-Z0-9.-]+\.[a-zA-Z]{2,}`)
+
+// Split reports whether a rune separates words, which is any unicode whitespace
+// including tabs and the non-breaking space of &nbsp;
+func Split(r rune) bool {
+	return unicode.IsSpace(r)
+}
+
+func extractWords(body []byte, config *Config, cache *wordCache) {
+	if len(config.ExcludeSelectors) > 0 {
+		body = removeSelectors(body, config.ExcludeSelectors)
+	}
+	if config.ExcludeHidden {
+		body = removeHidden(body)
+	}
+	if config.VisibleOnly {
+		body = removeSelectors(body, invisibleSelectors)
+	}
+	if config.Readability {
+		body = mainContent(body)
+	}
+	extractTokens(html.NewTokenizer(bytes.NewReader(body)), config, cache)
+}
+
+// extractTokens adds the words of all tokens of a document to the cache
+func extractTokens(domDoc *html.Tokenizer, config *Config, cache *wordCache) {
+	previousStartTokenTest := domDoc.Token()
+outer:
+	for {
+		tt := domDoc.Next()
+		switch {
+		case tt == html.ErrorToken:
+			break outer
+		case tt == html.StartTagToken:
+			previousStartTokenTest = domDoc.Token()
+			extractAttributes(previousStartTokenTest, config, cache)
+			extractFormField(previousStartTokenTest, config, cache)
+			extractMeta(previousStartTokenTest, config, cache)
+			extractMailto(previousStartTokenTest, config, cache)
+		case tt == html.SelfClosingTagToken:
+			token := domDoc.Token()
+			extractAttributes(token, config, cache)
+			extractFormField(token, config, cache)
+			extractMeta(token, config, cache)
+		case tt == html.CommentToken && config.IncludeComments:
+			// the tokenizer already strips the <!-- and --> delimiters
+			extractText(html.UnescapeString(string(domDoc.Text())), config, cache)
+		case tt == html.TextToken:
+			if previousStartTokenTest.Data == "script" && config.IncludeScripts {
+				extractScript(string(domDoc.Text()), config, cache)
+				continue
+			}
+			if previousStartTokenTest.Data == "script" || previousStartTokenTest.Data == "style" {
+				continue
+			}
+			extractText(html.UnescapeString(string(domDoc.Text())), config, cache)
+		}
+	}
+}
+
+// removeSelectors parses the document and removes the elements matching any of the CSS selectors.
+// On errors, the body is returned unchanged.
+func removeSelectors(body []byte, selectors []string) []byte {
+	document, err := goquery.NewDocumentFromReader(bytes.NewReader(body))
+	if err != nil {
+		return body
+	}
+	for _, selector := range selectors {
+		document.Find(selector).Remove()
+	}
+	stripped, err := document.Html()
+	if err != nil {
+		return body
+	}
+	return []byte(stripped)
+}
+
+// invisibleSelectors match the elements removed by VisibleOnly, which are never rendered
+var invisibleSelectors = []string{"head", "script", "style", "noscript", "template"}
+
+// hiddenStyleRegex matches inline styles hiding an element
+var hiddenStyleRegex = regexp.MustCompile(`(?i)(^|;)\s*(display\s*:\s*none|visibility\s*:\s*hidden)\s*(!important\s*)?(;|$)`)
+
+// removeHidden parses the document and removes elements with the hidden attribute or a style hiding them.
+// Only inline styles are evaluated, not stylesheets. On errors, the body is returned unchanged.
+func removeHidden(body []byte) []byte {
+	document, err := goquery.NewDocumentFromReader(bytes.NewReader(body))
+	if err != nil {
+		return body
+	}
+	document.Find("[hidden], [style]").FilterFunction(func(_ int, element *goquery.Selection) bool {
+		_, hidden := element.Attr("hidden")
+		return hidden || hiddenStyleRegex.MatchString(element.AttrOr("style", ""))
+	}).Remove()
+	stripped, err := document.Html()
+	if err != nil {
+		return body
+	}
+	return []byte(stripped)
+}
+
+// renderedLinks returns the targets of all links of a rendered page
+func renderedLinks(body []byte) []string {
+	document, err := goquery.NewDocumentFromReader(bytes.NewReader(body))
+	if err != nil {
+		return nil
+	}
+	return document.Find("a[href]").Map(func(_ int, link *goquery.Selection) string {
+		return link.AttrOr("href", "")
+	})
+}
+
+// mainContent returns the main content of a document like the text of an article, as detected by readability.
+// If nothing is detected, the whole document is returned.
+func mainContent(body []byte) []byte {
+	article, err := readability.FromReader(bytes.NewReader(body), nil)
+	if err != nil || strings.TrimSpace(article.TextContent) == "" {
+		return body
+	}
+	return []byte(article.Content)
+}
+
+// extractAttributes runs the values of the configured attributes of a tag through extractText
+func extractAttributes(token html.Token, config *Config, cache *wordCache) {
+	if len(config.IncludeAttrs) == 0 {
+		return
+	}
+	// alt and title frequently carry the same text, only count it once per tag
+	seen := []string{}
+	for _, attr := range token.Attr {
+		if !slices.Contains(config.IncludeAttrs, strings.ToLower(attr.Key)) || slices.Contains(seen, attr.Val) {
+			continue
+		}
+		seen = append(seen, attr.Val)
+		extractText(attr.Val, config, cache)
+	}
+}
+
+// formFieldTags are the elements whose attributes are extracted by IncludeFormFields
+var formFieldTags = []string{"input", "select", "textarea", "button"}
+
+// extractFormField runs the name, id and placeholder of a form field through extractText,
+// parameter names like old_password are valuable for recon
+func extractFormField(token html.Token, config *Config, cache *wordCache) {
+	if !config.IncludeFormFields || !slices.Contains(formFieldTags, token.Data) {
+		return
+	}
+	// name and id are often the same, only count it once per tag
+	seen := []string{}
+	for _, attr := range token.Attr {
+		switch strings.ToLower(attr.Key) {
+		case "name", "id", "placeholder":
+			if slices.Contains(seen, attr.Val) {
+				continue
+			}
+			seen = append(seen, attr.Val)
+			extractText(attr.Val, config, cache)
+		}
+	}
+}
+
+// extractMeta runs the content of description, keywords and og:* meta tags through extractText
+func extractMeta(token html.Token, config *Config, cache *wordCache) {
+	if config.NoMeta || token.Data != "meta" {
+		return
+	}
+	var name, content string
+	for _, attr := range token.Attr {
+		switch strings.ToLower(attr.Key) {
+		case "name", "property":
+			name = strings.ToLower(attr.Val)
+		case "content":
+			content = attr.Val
+		}
+	}
+	switch {
+	case name == "keywords":
+		// keywords are usually separated by commas without spaces
+		extractText(strings.ReplaceAll(content, ",", " "), config, cache)
+	case name == "description" || strings.HasPrefix(name, "og:"):
+		extractText(content, config, cache)
+	}
+}
+
+// extractMailto adds the addresses of mailto: links to the emails of the cache
+func extractMailto(token html.Token, config *Config, cache *wordCache) {
+	if !config.ExtractEmails || token.Data != "a" {
+		return
+	}
+	for _, attr := range token.Attr {
+		if strings.ToLower(attr.Key) != "href" || !strings.HasPrefix(strings.ToLower(attr.Val), "mailto:") {
+			continue
+		}
+		// mailto: may carry several addresses as well as a query like ?subject=
+		address, _, _ := strings.Cut(attr.Val[len("mailto:"):], "?")
+		if unescaped, err := url.PathUnescape(address); err == nil {
+			address = unescaped
+		}
+		extractEmails(address, cache)
+	}
+}
+
+// extractEmails adds all email addresses of a text to the cache, independent of the word filters
+func extractEmails(text string, cache *wordCache) {
+	for _, email := range emailRegex.FindAllString(text, -1) {
+		cache.AddEmail(email)
+	}
+}
+
+// extractText splits a text into words, filters them and adds them to the cache
+func extractText(text string, config *Config, cache *wordCache) {
+	TxtContent := strings.TrimSpace(text)
+	if len(TxtContent) == 0 {
+		return
+	}
+	// normalize before filtering, combining characters do not match the word regex
+	if config.Normalize {
+		TxtContent = norm.NFC.String(TxtContent)
+	}
+	if config.ExtractEmails {
+		extractEmails(TxtContent, cache)
+	}
+	if config.Language != "" && !matchesLanguage(TxtContent, config) {
+		return
+	}
+	trimChars := config.TrimChars
+	if config.NoTrim {
+		trimChars = ""
+	}
+	unfilteredWords := strings.FieldsFunc(TxtContent, Split)
+	if config.SplitChars != "" {
+		unfilteredWords = splitPunctuation(unfilteredWords, config.SplitChars, config.KeepOriginal)
+	}
+	if config.SplitIdentifiers {
+		var identifierParts []string
+		for _, word := range unfilteredWords {
+			identifierParts = append(identifierParts, splitIdentifier(strings.Trim(word, trimChars))...)
+		}
+		unfilteredWords = append(unfilteredWords, identifierParts...)
+	}
+	var filteredWords []string
+	for _, word := range unfilteredWords {
+		if leftoverEntityRegex.MatchString(word) {
+			continue
+		}
+		candidate := strings.Trim(word, trimChars)
+		if config.NoFilter {
+			filteredWords = append(filteredWords, candidate)
+		} else {
+			if config.WordRegex.MatchString(candidate) {
+				if length := utf8.RuneCountInString(candidate); length >= config.MinLen && length <= config.MaxLen && allPrintable(candidate) && uniqueChars(candidate) >= config.MinUniqueChars {
+					if config.MustContainDigit && strings.IndexFunc(candidate, unicode.IsDigit) < 0 {
+						continue
+					}
+					numeric := isNumeric(candidate)
+					if numeric && config.CollectNumbers {
+						cache.AddNumber(candidate)
+					}
+					if (config.NoNumeric && numeric) || (config.NumbersOnly && !numeric) {
+						continue
+					}
+					if !hasAffixes(candidate, config) {
+						continue
+					}
+					if config.OnlyASCII && !utf8string.NewString(candidate).IsASCII() {
+						continue
+					}
+					filteredWords = append(filteredWords, candidate)
+				}
+			}
+		}
+	}
+	for _, word := range filteredWords {
+		if config.Stopwords[strings.ToLower(word)] {
+			continue
+		}
+		switch config.Case {
+		case "lower":
+			word = strings.ToLower(word)
+		case "upper":
+			word = strings.ToUpper(word)
+		}
+		cache.Add(word)
+	}
+}
+
+// matchesLanguage checks that a text is not confidently detected as a language other than config.Language.
+// Detection works on the whole text block, single words can not be classified reliably.
+func matchesLanguage(text string, config *Config) bool {
+	info := whatlanggo.Detect(text)
+	if info.Confidence < config.LanguageConfidence {
+		return true
+	}
+	language := strings.ToLower(config.Language)
+	return info.Lang.Iso6391() == language || info.Lang.Iso6393() == language
+}
+
+// splitPunctuation splits words on any of the characters in chars, optionally keeping the original word
+func splitPunctuation(words []string, chars string, keepOriginal bool) []string {
+	var result []string
+	for _, word := range words {
+		parts := strings.FieldsFunc(word, func(r rune) bool {
+			return strings.ContainsRune(chars, r)
+		})
+		if keepOriginal && len(parts) > 1 {
+			result = append(result, word)
+		}
+		result = append(result, parts...)
+	}
+	return result
+}
+
+// splitIdentifier splits camelCase, snake_case, kebab-case and digit boundaries.
+// HTTPServer2_url becomes HTTP, Server, 2 and url. Returns nil if there is nothing to split.
+func splitIdentifier(word string) []string {
+	runes := []rune(word)
+	parts := []string{}
+	start := 0
+	for i, r := range runes {
+		if r == '_' || r == '-' {
+			if i > start {
+				parts = append(parts, string(runes[start:i]))
+			}
+			start = i + 1
+			continue
+		}
+		if i == start {
+			continue
+		}
+		previous := runes[i-1]
+		boundary := (unicode.IsLower(previous) && unicode.IsUpper(r)) ||
+			(unicode.IsDigit(previous) != unicode.IsDigit(r)) ||
+			(unicode.IsUpper(previous) && unicode.IsUpper(r) && i+1 < len(runes) && unicode.IsLower(runes[i+1]))
+		if boundary {
+			parts = append(parts, string(runes[start:i]))
+			start = i
+		}
+	}
+	if start < len(runes) {
+		parts = append(parts, string(runes[start:]))
+	}
+	if len(parts) < 2 {
+		return nil
+	}
+	return parts
+}
+
+// hasAffixes checks if a word starts with WordPrefix and ends with WordSuffix
+func hasAffixes(word string, config *Config) bool {
+	prefix, suffix := config.WordPrefix, config.WordSuffix
+	if config.IgnoreAffixCase {
+		word, prefix, suffix = strings.ToLower(word), strings.ToLower(prefix), strings.ToLower(suffix)
+	}
+	return strings.HasPrefix(word, prefix) && strings.HasSuffix(word, suffix)
+}
+
+// isNumeric checks if a word is made entirely of digits
+func isNumeric(word string) bool {
+	return strings.IndexFunc(word, func(r rune) bool { return !unicode.IsDigit(r) }) < 0
+}
+
+func uniqueChars(word string) int {
+	seen := make(map[rune]bool)
+	for _, rune := range word {
+		seen[rune] = true
+	}
+	return len(seen)
+}
+
+func allPrintable(word string) bool {
+	for _, rune := range word {
+		if !unicode.IsPrint(rune) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,378 @@
+/*
+Copyright © 2021 Michael Eder @edermi / twitter.com/michael_eder_
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package skweez
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+
+	"golang.org/x/net/html"
+)
+
+// extract runs ExtractWords on a page with the given text and the default config modified by configure
+func extract(t *testing.T, text string, configure func(config *Config)) map[string]int {
+	t.Helper()
+	config := DefaultConfig()
+	if configure != nil {
+		configure(&config)
+	}
+	return ExtractWords([]byte("<html><body><p>"+text+"</p></body></html>"), config)
+}
+
+func TestExtractWordsLengthBounds(t *testing.T) {
+	tests := []struct {
+		name   string
+		word   string
+		minLen int
+		maxLen int
+		want   bool
+	}{
+		{"below min", "ab", 3, 24, false},
+		{"at min", "abc", 3, 24, true},
+		{"above min", "abcd", 3, 24, true},
+		{"below max", strings.Repeat("a", 23), 3, 24, true},
+		{"at max", strings.Repeat("a", 24), 3, 24, true},
+		{"above max", strings.Repeat("a", 25), 3, 24, false},
+		{"min equals max", "abcde", 5, 5, true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			words := extract(t, test.word, func(config *Config) {
+				config.MinLen = test.minLen
+				config.MaxLen = test.maxLen
+			})
+			if got := words[test.word] == 1; got != test.want {
+				t.Errorf("word %q of length %d with bounds %d-%d: got %v, want %v", test.word, len(test.word), test.minLen, test.maxLen, got, test.want)
+			}
+		})
+	}
+}
+
+func TestExtractWordsLengthInRunes(t *testing.T) {
+	tests := []struct {
+		name   string
+		word   string
+		minLen int
+		maxLen int
+		want   bool
+	}{
+		// 4 runes in 5 bytes
+		{"umlaut at max", "Müll", 3, 4, true},
+		{"umlaut below min", "Müll", 5, 24, false},
+		// 4 runes in 12 bytes
+		{"CJK at max", "東京大学", 3, 4, true},
+		{"CJK above max", "東京大学院", 3, 4, false},
+		{"CJK at min", "東京大学", 4, 24, true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			words := extract(t, test.word, func(config *Config) {
+				config.WordRegex = ValidUnicodeWordRegex
+				config.MinLen = test.minLen
+				config.MaxLen = test.maxLen
+			})
+			if got := words[test.word] == 1; got != test.want {
+				t.Errorf("word %q with bounds %d-%d: got %v, want %v", test.word, test.minLen, test.maxLen, got, test.want)
+			}
+		})
+	}
+}
+
+// assertWords compares the extracted words along with their counts
+func assertWords(t *testing.T, got, want map[string]int) {
+	t.Helper()
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got words %v, want %v", got, want)
+	}
+}
+
+func TestExtractWordsUnicode(t *testing.T) {
+	text := "über café жена Straße hello"
+	t.Run("ascii", func(t *testing.T) {
+		assertWords(t, extract(t, text, nil), map[string]int{"Straße": 1, "hello": 1})
+	})
+	t.Run("unicode", func(t *testing.T) {
+		words := extract(t, text, func(config *Config) {
+			config.WordRegex = ValidUnicodeWordRegex
+		})
+		assertWords(t, words, map[string]int{"über": 1, "café": 1, "жена": 1, "Straße": 1, "hello": 1})
+	})
+}
+
+func TestExtractWordsMeta(t *testing.T) {
+	head := []byte(`<html><head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>Shop</title>
+<meta name="description" content="Handmade pottery">
+<meta name="keywords" content="ceramics,vases">
+<meta property="og:title" content="Pottery Studio">
+</head><body></body></html>`)
+	t.Run("meta", func(t *testing.T) {
+		want := map[string]int{"Shop": 1, "Handmade": 1, "pottery": 1, "ceramics": 1, "vases": 1, "Pottery": 1, "Studio": 1}
+		assertWords(t, ExtractWords(head, DefaultConfig()), want)
+	})
+	t.Run("no meta", func(t *testing.T) {
+		config := DefaultConfig()
+		config.NoMeta = true
+		assertWords(t, ExtractWords(head, config), map[string]int{"Shop": 1})
+	})
+}
+
+func TestExtractWordsCase(t *testing.T) {
+	text := "Login login LOGIN"
+	tests := []struct {
+		mode string
+		want map[string]int
+	}{
+		{"preserve", map[string]int{"Login": 1, "login": 1, "LOGIN": 1}},
+		{"lower", map[string]int{"login": 3}},
+		{"upper", map[string]int{"LOGIN": 3}},
+	}
+	for _, test := range tests {
+		t.Run(test.mode, func(t *testing.T) {
+			words := extract(t, text, func(config *Config) {
+				config.Case = test.mode
+			})
+			assertWords(t, words, test.want)
+		})
+	}
+}
+
+func TestSplitIdentifier(t *testing.T) {
+	tests := []struct {
+		word string
+		want []string
+	}{
+		{"getUserName", []string{"get", "User", "Name"}},
+		{"user_id", []string{"user", "id"}},
+		{"HTTPServer", []string{"HTTP", "Server"}},
+		{"parseHTTPResponse", []string{"parse", "HTTP", "Response"}},
+		{"get_userName2", []string{"get", "user", "Name", "2"}},
+		{"x-forwarded-for", []string{"x", "forwarded", "for"}},
+		{"password", nil},
+	}
+	for _, test := range tests {
+		if got := splitIdentifier(test.word); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("splitIdentifier(%q) = %v, want %v", test.word, got, test.want)
+		}
+	}
+}
+
+func TestExtractWordsSplitIdentifiers(t *testing.T) {
+	text := "getUserName user_id HTTPServer"
+	t.Run("off", func(t *testing.T) {
+		assertWords(t, extract(t, text, nil), map[string]int{"getUserName": 1, "user_id": 1, "HTTPServer": 1})
+	})
+	t.Run("on", func(t *testing.T) {
+		words := extract(t, text, func(config *Config) {
+			config.MinLen = 2
+			config.SplitIdentifiers = true
+		})
+		want := map[string]int{
+			"getUserName": 1, "get": 1, "User": 1, "Name": 1,
+			"user_id": 1, "user": 1, "id": 1,
+			"HTTPServer": 1, "HTTP": 1, "Server": 1,
+		}
+		assertWords(t, words, want)
+	})
+}
+
+func TestExtractWordsEntities(t *testing.T) {
+	text := "Tom&nbsp;&amp;&nbsp;Jerry don&#8217;t &amp;amp; &amp;nbsp; rock&nbsp;roll"
+	want := map[string]int{"Tom": 1, "Jerry": 1, "don’t": 1, "rock": 1, "roll": 1}
+	assertWords(t, extract(t, text, nil), want)
+}
+
+func TestExtractWordsWhitespace(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+	}{
+		{"tabs", "alpha\tbeta\t\tgamma"},
+		{"newlines", "alpha\nbeta\r\ngamma"},
+		{"non-breaking space", "alpha\u00a0beta\u00a0gamma"},
+		{"mixed", "alpha \t\n beta\u00a0\tgamma"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assertWords(t, extract(t, test.text, nil), map[string]int{"alpha": 1, "beta": 1, "gamma": 1})
+		})
+	}
+}
+
+func TestExtractWordsSplitPunctuation(t *testing.T) {
+	text := "e-mail don't don’t cyber/security"
+	tests := []struct {
+		name         string
+		splitChars   string
+		keepOriginal bool
+		want         map[string]int
+	}{
+		{"off", "", false, map[string]int{"e-mail": 1, "don't": 1, "don’t": 1, "cyber/security": 1}},
+		{"split", DefaultSplitChars, false, map[string]int{"mail": 1, "don": 2, "cyber": 1, "security": 1}},
+		{"keep original", DefaultSplitChars, true, map[string]int{
+			"e-mail": 1, "mail": 1, "don't": 1, "don’t": 1, "don": 2, "cyber/security": 1, "cyber": 1, "security": 1,
+		}},
+		{"custom separators", "/", false, map[string]int{"e-mail": 1, "don't": 1, "don’t": 1, "cyber": 1, "security": 1}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			words := extract(t, text, func(config *Config) {
+				config.MinLen = 2
+				config.SplitChars = test.splitChars
+				config.KeepOriginal = test.keepOriginal
+			})
+			assertWords(t, words, test.want)
+		})
+	}
+}
+
+func TestExtractWordsSample(t *testing.T) {
+	page := []byte(`<!DOCTYPE html>
+<html><head><title>Company Portal</title><style>body { color: red }</style></head>
+<body>
+<!-- internal note -->
+<h1>Welcome back</h1>
+<p>Please <a href="/login">login</a> with your <b>employee</b> account.</p>
+<img src="logo.png" alt="company logo">
+<script>var welcome = "script";</script>
+<br/>Welcome again
+</body></html>`)
+	config := DefaultConfig()
+	config.IncludeComments = true
+	config.IncludeAttrs = []string{"alt"}
+	// the tokenizer used to read from a string copy of the body
+	before := newWordCache()
+	extractTokens(html.NewTokenizer(strings.NewReader(string(page))), &config, before)
+	after := newWordCache()
+	extractTokens(html.NewTokenizer(bytes.NewReader(page)), &config, after)
+	if len(after.words) == 0 {
+		t.Fatal("no words extracted")
+	}
+	assertWords(t, after.words, before.words)
+}
+
+func TestExtractWordsFilterAfterTrim(t *testing.T) {
+	// the zero width space is neither printable nor ASCII, but trimmed from the edges of the word
+	text := "\u200bsecret\u200b pass\u200bword"
+	tests := []struct {
+		name      string
+		onlyASCII bool
+	}{
+		{"printable", false},
+		{"only ascii", true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			words := extract(t, text, func(config *Config) {
+				config.TrimChars = DefaultTrimChars + "\u200b"
+				config.OnlyASCII = test.onlyASCII
+			})
+			assertWords(t, words, map[string]int{"secret": 1})
+		})
+	}
+}
+
+func TestExtractWordsMinUniqueChars(t *testing.T) {
+	// bääb has 2 distinct runes, but 3 distinct bytes
+	text := "aaaa 1111 abab bääb abcd password"
+	words := extract(t, text, func(config *Config) {
+		config.MinUniqueChars = 3
+	})
+	assertWords(t, words, map[string]int{"abcd": 1, "password": 1})
+}
+
+func TestExtractWordsNormalize(t *testing.T) {
+	// precomposed é and e followed by a combining acute accent
+	text := "caf\u00e9 cafe\u0301 re\u0301sum\u00e9"
+	t.Run("off", func(t *testing.T) {
+		words := extract(t, text, func(config *Config) {
+			config.WordRegex = ValidUnicodeWordRegex
+		})
+		// the decomposed café ends in a combining mark and is rejected by the word regex
+		assertWords(t, words, map[string]int{"caf\u00e9": 1, "re\u0301sum\u00e9": 1})
+	})
+	t.Run("on", func(t *testing.T) {
+		words := extract(t, text, func(config *Config) {
+			config.WordRegex = ValidUnicodeWordRegex
+			config.Normalize = true
+		})
+		assertWords(t, words, map[string]int{"caf\u00e9": 2, "r\u00e9sum\u00e9": 1})
+	})
+}
+
+func TestExtractWordsNumbers(t *testing.T) {
+	text := "2024 v2 abc123 1999"
+	tests := []struct {
+		name      string
+		configure func(config *Config)
+		want      map[string]int
+	}{
+		{"default", nil, map[string]int{"2024": 1, "v2": 1, "abc123": 1, "1999": 1}},
+		{"no numeric", func(config *Config) { config.NoNumeric = true }, map[string]int{"v2": 1, "abc123": 1}},
+		{"numbers only", func(config *Config) { config.NumbersOnly = true }, map[string]int{"2024": 1, "1999": 1}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			words := extract(t, text, func(config *Config) {
+				config.MinLen = 2
+				if test.configure != nil {
+					test.configure(config)
+				}
+			})
+			assertWords(t, words, test.want)
+		})
+	}
+	t.Run("collect numbers", func(t *testing.T) {
+		config := DefaultConfig()
+		config.MinLen = 2
+		config.NoNumeric = true
+		config.CollectNumbers = true
+		// numbers are collected apart from the words even if they are dropped from them
+		extracted := ExtractAll([]byte("<html><body><p>"+text+"</p></body></html>"), config)
+		assertWords(t, extracted.Words, map[string]int{"v2": 1, "abc123": 1})
+		if want := []string{"1999", "2024"}; !reflect.DeepEqual(extracted.Numbers, want) {
+			t.Errorf("got numbers %v, want %v", extracted.Numbers, want)
+		}
+	})
+}
+
+func TestExtractWordsVisibleOnly(t *testing.T) {
+	page := []byte(`<html><head><title>Headtitle</title><meta name="description" content="metadesc"></head>
+<body><p>Visible paragraph</p><noscript>Enable javascript</noscript><template><p>Templated</p></template>
+<script>var scripted = 1</script><style>.styled{}</style><select><option>Optionvalue</option></select></body></html>`)
+	t.Run("tokenizer", func(t *testing.T) {
+		// the tokenizer skips scripts and styles, but not the head, noscript and templates
+		want := map[string]int{"Headtitle": 1, "metadesc": 1, "Visible": 1, "paragraph": 1, "Enable": 1, "javascript": 1, "Templated": 1, "Optionvalue": 1}
+		assertWords(t, ExtractWords(page, DefaultConfig()), want)
+	})
+	t.Run("visible only", func(t *testing.T) {
+		config := DefaultConfig()
+		config.VisibleOnly = true
+		want := map[string]int{"Visible": 1, "paragraph": 1, "Optionvalue": 1}
+		assertWords(t, ExtractWords(page, config), want)
+	})
+}
+
+func TestExtractWordsZeroConfig(t *testing.T) {
+	page := []byte("<html><body><p>a an the password verylongwordexceedingthelimit</p></body></html>")
+	assertWords(t, ExtractWords(page, Config{}), map[string]int{"the": 1, "password": 1})
+}
@@ -0,0 +1,136 @@
+/*
+Copyright © 2021 Michael Eder @edermi / twitter.com/michael_eder_
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package skweez
+
+import (
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// manglingYears is the number of years up to the current one appended by the append-year rule
+const manglingYears = 10
+
+// ManglingRules are the rules supported by Mangle
+var ManglingRules = map[string]func(word string) []string{
+	// capitalize turns password into Password
+	"capitalize": func(word string) []string {
+		runes := []rune(strings.ToLower(word))
+		if len(runes) == 0 {
+			return nil
+		}
+		runes[0] = unicode.ToUpper(runes[0])
+		return []string{string(runes)}
+	},
+	// upper turns password into PASSWORD
+	"upper": func(word string) []string {
+		return []string{strings.ToUpper(word)}
+	},
+	// append-digit turns password into password0 to password9
+	"append-digit": func(word string) []string {
+		variants := make([]string, 0, 10)
+		for digit := 0; digit <= 9; digit++ {
+			variants = append(variants, word+strconv.Itoa(digit))
+		}
+		return variants
+	},
+	// append-year appends the last years up to the current one, like password2024
+	"append-year": func(word string) []string {
+		variants := make([]string, 0, manglingYears)
+		year := time.Now().Year()
+		for i := manglingYears - 1; i >= 0; i-- {
+			variants = append(variants, word+strconv.Itoa(year-i))
+		}
+		return variants
+	},
+}
+
+// DefaultManglingRules are applied by Mangle if no rules are given
+var DefaultManglingRules = []string{"capitalize", "append-digit", "append-year"}
+
+// Mangle applies each of the rules to a word and returns the resulting variants in the order of the rules.
+// Rules are not chained and variants equal to the word are left out, unknown rules are ignored.
+func Mangle(word string, rules []string) []string {
+	if len(rules) == 0 {
+		rules = DefaultManglingRules
+	}
+	var variants []string
+	for _, rule := range rules {
+		apply, ok := ManglingRules[rule]
+		if !ok {
+			continue
+		}
+		for _, variant := range apply(word) {
+			if variant != word {
+				variants = append(variants, variant)
+			}
+		}
+	}
+	return variants
+}
+
+// leetSubstitutions are the characters replaced by LeetVariants
+var leetSubstitutions = map[rune]rune{
+	'a': '4',
+	'e': '3',
+	'i': '1',
+	'o': '0',
+	's': '5',
+}
+
+// LeetVariants returns the leetspeak variants of a word like p4ssword or p4ssw0rd, replacing at most
+// maxSubstitutions characters. The variants are ordered by the number of substitutions and their positions.
+func LeetVariants(word string, maxSubstitutions int) []string {
+	runes := []rune(word)
+	var positions []int
+	for i, r := range runes {
+		if _, ok := leetSubstitutions[unicode.ToLower(r)]; ok {
+			positions = append(positions, i)
+		}
+	}
+	var variants []string
+	for substitutions := 1; substitutions <= maxSubstitutions && substitutions <= len(positions); substitutions++ {
+		combinations(len(positions), substitutions, func(chosen []int) {
+			variant := make([]rune, len(runes))
+			copy(variant, runes)
+			for _, i := range chosen {
+				variant[positions[i]] = leetSubstitutions[unicode.ToLower(runes[positions[i]])]
+			}
+			variants = append(variants, string(variant))
+		})
+	}
+	return variants
+}
+
+// combinations calls fn with every ascending choice of k out of n indexes
+func combinations(n, k int, fn func(chosen []int)) {
+	chosen := make([]int, k)
+	var choose func(start, depth int)
+	choose = func(start, depth int) {
+		if depth == k {
+			fn(chosen)
+			return
+		}
+		for i := start; i <= n-(k-depth); i++ {
+			chosen[depth] = i
+			choose(i+1, depth+1)
+		}
+	}
+	choose(0, 0)
+}
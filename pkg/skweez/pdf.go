@@ -0,0 +1,57 @@
+//go:build pdf
+
+/*
+Copyright © 2021 Michael Eder @edermi / twitter.com/michael_eder_
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package skweez
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+
+	"github.com/ledongthuc/pdf"
+)
+
+// pdfSupport reports whether PDF documents can be parsed, which this build can
+func pdfSupport() error {
+	return nil
+}
+
+// extractPDF runs the plain text of a PDF document through extractText
+func extractPDF(body []byte, config *Config, cache *wordCache) (err error) {
+	// the PDF parser panics on some malformed documents
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			err = fmt.Errorf("parsing PDF failed: %v", recovered)
+		}
+	}()
+	reader, err := pdf.NewReader(bytes.NewReader(body), int64(len(body)))
+	if err != nil {
+		return err
+	}
+	plainText, err := reader.GetPlainText()
+	if err != nil {
+		return err
+	}
+	text, err := io.ReadAll(plainText)
+	if err != nil {
+		return err
+	}
+	extractText(string(text), config, cache)
+	return nil
+}
@@ -0,0 +1,33 @@
+//go:build !pdf
+
+/*
+Copyright © 2021 Michael Eder @edermi / twitter.com/michael_eder_
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package skweez
+
+import "errors"
+
+// errNoPDFSupport is returned in builds without the pdf tag, which pulls in the PDF parser
+var errNoPDFSupport = errors.New("extracting PDF documents requires skweez to be built with -tags pdf")
+
+func pdfSupport() error {
+	return errNoPDFSupport
+}
+
+func extractPDF(body []byte, config *Config, cache *wordCache) error {
+	return errNoPDFSupport
+}
@@ -0,0 +1,153 @@
+//go:build render
+
+/*
+Copyright © 2021 Michael Eder @edermi / twitter.com/michael_eder_
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package skweez
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/chromedp/cdproto/fetch"
+	"github.com/chromedp/cdproto/network"
+	"github.com/chromedp/chromedp"
+)
+
+// renderWait gives scripts some time to fetch and insert content after the page is loaded
+const renderWait = time.Second
+
+// defaultRenderTimeout limits the rendering of a page if Config.RequestTimeout is not set
+const defaultRenderTimeout = 30 * time.Second
+
+// renderer loads pages in a headless Chrome, every page is rendered in its own tab
+type renderer struct {
+	browser context.Context
+	cancel  context.CancelFunc
+	timeout time.Duration
+	config  *Config
+}
+
+// newRenderer starts the headless browser. Chrome only supports a single proxy, the first one is used.
+// Client certificates and CA certificates can not be passed to Chrome.
+func newRenderer(config *Config) (*renderer, error) {
+	if config.ClientCert != "" || config.CACert != "" {
+		return nil, errors.New("rendering pages does not support client certificates or a CA certificate")
+	}
+	options := chromedp.DefaultExecAllocatorOptions[:]
+	if config.UserAgent != "" {
+		options = append(options, chromedp.UserAgent(config.UserAgent))
+	}
+	if len(config.Proxies) > 0 {
+		options = append(options, chromedp.ProxyServer(config.Proxies[0]))
+	}
+	if config.Insecure {
+		options = append(options, chromedp.IgnoreCertErrors)
+	}
+	allocator, cancelAllocator := chromedp.NewExecAllocator(context.Background(), options...)
+	browser, cancelBrowser := chromedp.NewContext(allocator)
+	// the first Run starts the browser
+	if err := chromedp.Run(browser); err != nil {
+		cancelBrowser()
+		cancelAllocator()
+		return nil, fmt.Errorf("could not start headless Chrome: %w", err)
+	}
+	timeout := config.RequestTimeout
+	if timeout <= 0 {
+		timeout = defaultRenderTimeout
+	}
+	return &renderer{
+		browser: browser,
+		cancel: func() {
+			cancelBrowser()
+			cancelAllocator()
+		},
+		timeout: timeout,
+		config:  config,
+	}, nil
+}
+
+// Render returns the HTML of the DOM of a page after running its scripts. The page is requested
+// with the headers of the config and the given cookies, usually those of the crawl for the URL.
+func (renderer *renderer) Render(uri string, cookies []*http.Cookie) ([]byte, error) {
+	tab, cancel := chromedp.NewContext(renderer.browser)
+	defer cancel()
+	tab, cancelTimeout := context.WithTimeout(tab, renderer.timeout)
+	defer cancelTimeout()
+	actions := []chromedp.Action{network.Enable(), network.SetExtraHTTPHeaders(renderer.headers())}
+	for _, cookie := range cookies {
+		actions = append(actions, network.SetCookie(cookie.Name, cookie.Value).WithURL(uri))
+	}
+	if renderer.config.BasicAuth != "" {
+		renderer.handleAuth(tab)
+		actions = append(actions, fetch.Enable().WithHandleAuthRequests(true))
+	}
+	var html string
+	actions = append(actions,
+		chromedp.Navigate(uri),
+		chromedp.Sleep(renderWait),
+		chromedp.OuterHTML("html", &html, chromedp.ByQuery),
+	)
+	err := chromedp.Run(tab, actions...)
+	return []byte(html), err
+}
+
+// headers returns the headers of the config, which are added to every request like in the crawl
+func (renderer *renderer) headers() network.Headers {
+	headers := network.Headers{}
+	if renderer.config.AcceptLanguage != "" {
+		headers["Accept-Language"] = renderer.config.AcceptLanguage
+	}
+	for _, header := range renderer.config.Headers {
+		if headerSplit := strings.SplitN(header, ":", 2); len(headerSplit) > 1 {
+			headers[strings.TrimSpace(headerSplit[0])] = strings.TrimSpace(headerSplit[1])
+		}
+	}
+	return headers
+}
+
+// handleAuth answers basic authentication challenges of a tab with the credentials of the config,
+// but only for the targets and scope. Intercepting the challenges pauses all requests, which are continued.
+func (renderer *renderer) handleAuth(tab context.Context) {
+	user, password, _ := strings.Cut(renderer.config.BasicAuth, ":")
+	chromedp.ListenTarget(tab, func(event interface{}) {
+		switch event := event.(type) {
+		case *fetch.EventRequestPaused:
+			go chromedp.Run(tab, fetch.ContinueRequest(event.RequestID))
+		case *fetch.EventAuthRequired:
+			response := &fetch.AuthChallengeResponse{Response: fetch.AuthChallengeResponseResponseCancelAuth}
+			if uri, err := url.Parse(event.Request.URL); err == nil && credentialsAllowed(renderer.config, uri) {
+				response = &fetch.AuthChallengeResponse{
+					Response: fetch.AuthChallengeResponseResponseProvideCredentials,
+					Username: user,
+					Password: password,
+				}
+			}
+			go chromedp.Run(tab, fetch.ContinueWithAuth(event.RequestID, response))
+		}
+	})
+}
+
+// Close stops the browser
+func (renderer *renderer) Close() {
+	renderer.cancel()
+}
@@ -0,0 +1,38 @@
+//go:build !render
+
+/*
+Copyright © 2021 Michael Eder @edermi / twitter.com/michael_eder_
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package skweez
+
+import (
+	"errors"
+	"net/http"
+)
+
+// renderer is only available in builds with the render tag, which pulls in a headless Chrome client
+type renderer struct{}
+
+func newRenderer(config *Config) (*renderer, error) {
+	return nil, errors.New("rendering pages requires skweez to be built with -tags render")
+}
+
+func (renderer *renderer) Render(uri string, cookies []*http.Cookie) ([]byte, error) {
+	return nil, errors.New("rendering is not supported")
+}
+
+func (renderer *renderer) Close() {}
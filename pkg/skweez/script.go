@@ -0,0 +1,82 @@
+/*
+Copyright © 2021 Michael Eder @edermi / twitter.com/michael_eder_
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package skweez
+
+import (
+	"strings"
+)
+
+// scriptKeywords are JavaScript keywords, which would otherwise end up in every word list
+var scriptKeywords = map[string]bool{
+	"await": true, "break": true, "case": true, "catch": true, "class": true, "const": true,
+	"continue": true, "debugger": true, "default": true, "delete": true, "do": true, "else": true,
+	"export": true, "extends": true, "false": true, "finally": true, "for": true, "function": true,
+	"if": true, "import": true, "in": true, "instanceof": true, "let": true, "new": true,
+	"null": true, "return": true, "super": true, "switch": true, "this": true, "throw": true,
+	"true": true, "try": true, "typeof": true, "undefined": true, "var": true, "void": true,
+	"while": true, "with": true, "yield": true,
+}
+
+// extractScript runs the string literals and identifiers of JavaScript source through extractText.
+// It is a simple tokenizer rather than a parser, regular expression literals and the like are
+// treated as code, which only adds some noise.
+func extractScript(source string, config *Config, cache *wordCache) {
+	var identifiers []string
+	runes := []rune(source)
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+		switch {
+		case r == '/' && i+1 < len(runes) && runes[i+1] == '/':
+			// line comment
+			for i < len(runes) && runes[i] != '\n' {
+				i++
+			}
+		case r == '/' && i+1 < len(runes) && runes[i+1] == '*':
+			// block comment
+			i += 2
+			for i+1 < len(runes) && !(runes[i] == '*' && runes[i+1] == '/') {
+				i++
+			}
+			i++
+		case r == '"' || r == '\'' || r == '`':
+			start := i + 1
+			for i++; i < len(runes) && runes[i] != r; i++ {
+				if runes[i] == '\\' {
+					i++
+				}
+			}
+			if start < len(runes) {
+				extractText(string(runes[start:i]), config, cache)
+			}
+		case isIdentifierRune(r, true):
+			start := i
+			for i+1 < len(runes) && isIdentifierRune(runes[i+1], false) {
+				i++
+			}
+			if identifier := string(runes[start : i+1]); !scriptKeywords[identifier] {
+				identifiers = append(identifiers, identifier)
+			}
+		}
+	}
+	extractText(strings.Join(identifiers, " "), config, cache)
+}
+
+// isIdentifierRune reports whether r may be part of a JavaScript identifier, digits only after the first rune
+func isIdentifierRune(r rune, first bool) bool {
+	return r == '_' || r == '$' || (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (!first && r >= '0' && r <= '9')
+}
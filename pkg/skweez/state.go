@@ -0,0 +1,134 @@
+/*
+Copyright © 2021 Michael Eder @edermi / twitter.com/michael_eder_
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package skweez
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// stateInterval is the time between two saves of the state file
+const stateInterval = 30 * time.Second
+
+// crawlState tracks the visited and pending URLs of a crawl, so an interrupted crawl can be resumed
+type crawlState struct {
+	mu   sync.Mutex
+	path string
+	// visited holds the URLs that were requested and finished, successfully or not
+	visited map[string]bool
+	// pending maps the URLs requested but not finished yet to their remaining depth, 0 = unlimited
+	pending map[string]int
+}
+
+// stateFile is the format of the state file
+type stateFile struct {
+	Visited []string       `json:"visited"`
+	Pending map[string]int `json:"pending"`
+	Words   map[string]int `json:"words"`
+}
+
+// loadState reads the state file if it exists and returns the state along with the words counted so far
+func loadState(path string) (*crawlState, map[string]int, error) {
+	state := &crawlState{path: path, visited: make(map[string]bool), pending: make(map[string]int)}
+	content, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return state, nil, nil
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	var saved stateFile
+	if err := json.Unmarshal(content, &saved); err != nil {
+		return nil, nil, fmt.Errorf("could not parse state file %s: %w", path, err)
+	}
+	for _, visited := range saved.Visited {
+		state.visited[visited] = true
+	}
+	for pending, depth := range saved.Pending {
+		state.pending[pending] = depth
+	}
+	return state, saved.Words, nil
+}
+
+// Visited reports whether a URL was already visited
+func (state *crawlState) Visited(url string) bool {
+	state.mu.Lock()
+	defer state.mu.Unlock()
+	return state.visited[url]
+}
+
+// Requested marks a URL as pending with its remaining depth
+func (state *crawlState) Requested(url string, depth int) {
+	state.mu.Lock()
+	defer state.mu.Unlock()
+	state.pending[url] = depth
+}
+
+// Finished marks a URL as visited
+func (state *crawlState) Finished(url string) {
+	state.mu.Lock()
+	defer state.mu.Unlock()
+	delete(state.pending, url)
+	state.visited[url] = true
+}
+
+// Pending returns the pending URLs with their remaining depth and clears them, they are requested again
+func (state *crawlState) Pending() map[string]int {
+	state.mu.Lock()
+	defer state.mu.Unlock()
+	pending := state.pending
+	state.pending = make(map[string]int)
+	return pending
+}
+
+// Save writes the state along with the words to the state file. The file is replaced atomically,
+// so it is not corrupted if skweez is killed while saving.
+func (state *crawlState) Save(words map[string]int) error {
+	state.mu.Lock()
+	saved := stateFile{Pending: make(map[string]int, len(state.pending)), Words: words}
+	for visited := range state.visited {
+		saved.Visited = append(saved.Visited, visited)
+	}
+	for pending, depth := range state.pending {
+		saved.Pending[pending] = depth
+	}
+	state.mu.Unlock()
+	content, err := json.Marshal(saved)
+	if err != nil {
+		return err
+	}
+	temp, err := os.CreateTemp(filepath.Dir(state.path), filepath.Base(state.path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(temp.Name())
+	if _, err := temp.Write(content); err != nil {
+		temp.Close()
+		return err
+	}
+	if err := temp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(temp.Name(), state.path)
+}
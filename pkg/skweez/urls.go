@@ -0,0 +1,90 @@
+/*
+Copyright © 2021 Michael Eder @edermi / twitter.com/michael_eder_
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package skweez
+
+import (
+	"net/url"
+	"strings"
+	"sync"
+)
+
+// stripQueryParams removes the given query parameters from a URL, for example session IDs,
+// so that colly recognizes otherwise identical URLs as already visited
+func stripQueryParams(uri string, params []string) string {
+	if len(params) == 0 {
+		return uri
+	}
+	parsed, err := url.Parse(uri)
+	if err != nil || parsed.RawQuery == "" {
+		return uri
+	}
+	query := parsed.Query()
+	for _, param := range params {
+		query.Del(param)
+	}
+	parsed.RawQuery = query.Encode()
+	return parsed.String()
+}
+
+// normalizeURL removes the fragment of a URL and optionally the trailing slash of its path,
+// so that colly recognizes links to /page, /page/ and /page#section as the same URL
+func normalizeURL(uri string, trailingSlash bool) string {
+	parsed, err := url.Parse(uri)
+	if err != nil {
+		return uri
+	}
+	parsed.Fragment = ""
+	parsed.RawFragment = ""
+	if trailingSlash {
+		parsed.Path = strings.TrimRight(parsed.Path, "/")
+		parsed.RawPath = strings.TrimRight(parsed.RawPath, "/")
+		if parsed.Path == "" && parsed.Host != "" {
+			parsed.Path = "/"
+		}
+	}
+	return parsed.String()
+}
+
+// hasQuery checks if a URL has a query string, which may be empty like in /search?
+func hasQuery(uri string) bool {
+	parsed, err := url.Parse(uri)
+	return err == nil && (parsed.RawQuery != "" || parsed.ForceQuery)
+}
+
+// pathCounter counts visits per host and path, ignoring the query, to detect crawler traps
+// like endlessly incrementing page parameters
+type pathCounter struct {
+	mu     sync.Mutex
+	visits map[string]int
+}
+
+func newPathCounter() *pathCounter {
+	return &pathCounter{visits: make(map[string]int)}
+}
+
+// Allow counts a visit of the URL's path and reports whether it is within the limit
+func (pc *pathCounter) Allow(uri *url.URL, limit int) bool {
+	pc.mu.Lock()
+	defer pc.mu.Unlock()
+	key := uri.Host + uri.Path
+	if pc.visits[key] >= limit {
+		return false
+	}
+	pc.visits[key] += 1
+	return true
+}
@@ -0,0 +1,43 @@
+/*
+Copyright © 2021 Michael Eder @edermi / twitter.com/michael_eder_
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package skweez
+
+import "testing"
+
+func TestNormalizeURL(t *testing.T) {
+	tests := []struct {
+		uri           string
+		trailingSlash bool
+		want          string
+	}{
+		{"https://example.com/page#section", false, "https://example.com/page"},
+		{"https://example.com/page/#section", false, "https://example.com/page/"},
+		{"https://example.com/page/", false, "https://example.com/page/"},
+		{"https://example.com/page/", true, "https://example.com/page"},
+		{"https://example.com/page/#section", true, "https://example.com/page"},
+		{"https://example.com/page/?q=1#section", true, "https://example.com/page?q=1"},
+		{"https://example.com/", true, "https://example.com/"},
+		{"https://example.com", true, "https://example.com/"},
+		{"https://example.com#top", false, "https://example.com"},
+	}
+	for _, test := range tests {
+		if got := normalizeURL(test.uri, test.trailingSlash); got != test.want {
+			t.Errorf("normalizeURL(%q, %v) = %q, want %q", test.uri, test.trailingSlash, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,82 @@
+/*
+Copyright © 2021 Michael Eder @edermi / twitter.com/michael_eder_
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package skweez
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"io"
+	"strings"
+
+	"github.com/gocolly/colly"
+	"golang.org/x/exp/slices"
+)
+
+// xmlContentTypes are the media types of XML documents like RSS and Atom feeds. Other XML based types
+// are not included on purpose, XHTML is processed as HTML and SVG images are no text.
+var xmlContentTypes = []string{"application/xml", "text/xml", "application/rss+xml", "application/atom+xml"}
+
+// isXML checks the Content-Type of a response for XML documents like RSS and Atom feeds
+func isXML(r *colly.Response) bool {
+	return slices.Contains(xmlContentTypes, mediaType(r))
+}
+
+// extractXML runs the text and attributes of all elements of an XML document through extractText.
+// Feeds often embed escaped HTML in their elements, which is handed to extractWords instead.
+// Words found before a syntax error in malformed documents are kept.
+func extractXML(body []byte, config *Config, cache *wordCache) error {
+	decoder := xml.NewDecoder(bytes.NewReader(body))
+	decoder.Strict = false
+	decoder.AutoClose = xml.HTMLAutoClose
+	decoder.Entity = xml.HTMLEntity
+	// the encoding declaration is ignored, most feeds are UTF-8 anyways
+	decoder.CharsetReader = func(charset string, input io.Reader) (io.Reader, error) {
+		return input, nil
+	}
+	// readability needs a full document, not the fragments of a feed
+	fragmentConfig := *config
+	fragmentConfig.Readability = false
+	for {
+		token, err := decoder.Token()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		switch token := token.(type) {
+		case xml.StartElement:
+			for _, attr := range token.Attr {
+				if slices.Contains(config.IncludeAttrs, strings.ToLower(attr.Name.Local)) {
+					extractText(attr.Value, config, cache)
+				}
+			}
+		case xml.CharData:
+			if bytes.ContainsRune(token, '<') {
+				extractWords(token, &fragmentConfig, cache)
+			} else {
+				extractText(string(token), config, cache)
+			}
+		case xml.Comment:
+			if config.IncludeComments {
+				extractText(string(token), config, cache)
+			}
+		}
+	}
+}